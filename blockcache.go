@@ -4,21 +4,28 @@ package httpseek
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/sync/singleflight"
 )
 
 // BlockCache defines a minimal interface for storing block responses.
+// Close releases any underlying resources (file handles, mappings); it is a
+// no-op for purely in-memory implementations.
 type BlockCache interface {
 	Clear()
 	Delete(block int64)
 	Get(block int64) ([]byte, bool)
 	Put(block int64, data []byte)
+	Close() error
 }
 
 // MemoryBlockCache is a simple in-memory implementation.
@@ -56,6 +63,9 @@ func (c *MemoryBlockCache) Put(block int64, v []byte) {
 	c.m[block] = v
 }
 
+// Close is a no-op; MemoryBlockCache holds no resources beyond the Go heap.
+func (c *MemoryBlockCache) Close() error { return nil }
+
 // CachedBlockTransport caches aligned Range GET responses.
 // It transparently rounds incoming Range headers to fixed-size blocks.
 // Each block is stored once and reused for any overlapping request.
@@ -63,15 +73,85 @@ type CachedBlockTransport struct {
 	Transport http.RoundTripper
 	Cache     BlockCache
 	BlockSize int64
-	group     singleflight.Group
+
+	// ReadAhead, when > 0, prefetches the next ReadAhead blocks in the
+	// background once consecutive requests for the same URL look
+	// sequential. It has no effect on access patterns that look random.
+	// Call WithNoReadAhead on a request's context to disable it for that
+	// request regardless of this setting.
+	ReadAhead int
+
+	// Parallelism, when > 1, splits a single missing block run into up to
+	// Parallelism contiguous sub-runs and fetches them concurrently through
+	// Transport. Each sub-run is still deduplicated via singleflight on its
+	// own block-range key, so two callers racing on the same sub-run share
+	// one fetch. Zero or one means fetch each run with a single request.
+	Parallelism int
+
+	// Retry configures retry-with-backoff for the underlying block fetch:
+	// network errors, 5xx/429 responses (honoring Retry-After), and
+	// truncated 206 bodies all get retried per policy. Nil (the default)
+	// disables retries, matching ReaderAtHTTP.Retry.
+	Retry *RetryPolicy
+
+	// MaxRangesPerRequest caps how many missing block runs go into a single
+	// multi-range request; some servers refuse a multipart/byteranges
+	// response with too many parts. Zero uses defaultMaxRangesPerRequest.
+	MaxRangesPerRequest int
+
+	// PrefetchWorkers bounds the number of concurrent prefetch fetches.
+	// Zero uses defaultPrefetchWorkers.
+	PrefetchWorkers int
+
+	// OnCacheEvent, if set, is wired into Cache's hit/miss/eviction events
+	// when Cache implements eventedBlockCache (currently just
+	// LRUMemoryBlockCache). It lets a caller observe eviction pressure
+	// without the transport hard-coding a specific cache implementation.
+	OnCacheEvent func(CacheEvent)
+
+	group singleflight.Group
+
+	cacheEventOnce sync.Once
+
+	prefetchOnce sync.Once
+	prefetchSem  chan struct{}
+
+	mu      sync.Mutex
+	nextSeq map[string]int64 // url -> block expected to start the next sequential request
+
+	// urlMeta records each URL's first-seen ETag/Last-Modified, applied as
+	// If-Match/If-Unmodified-Since on later fetches for that URL so a
+	// resource mutated underneath the cache surfaces as a 412 Precondition
+	// Failed instead of silently stitching together stale and fresh blocks.
+	urlMeta sync.Map
+
+	urlBlocksMu sync.Mutex
+	urlBlocks   map[string]map[int64]struct{} // url -> blocks cached under its current validators
+
+	// Metrics, safe for concurrent use via atomic ops.
+	HitCount       int64
+	MissCount      int64
+	PrefetchCount  int64
+	CoalescedCount int64
 }
 
+// defaultPrefetchWorkers bounds background prefetch fetches when
+// PrefetchWorkers is unset.
+const defaultPrefetchWorkers = 4
+
 // Compile-time check
 var _ http.RoundTripper = (*CachedBlockTransport)(nil)
 
 // DefaultBlockSize is the default block alignment size.
 const DefaultBlockSize = 512
 
+// eventedBlockCache is implemented by a BlockCache that reports its own
+// hit/miss/eviction events, letting CachedBlockTransport forward them
+// through OnCacheEvent without depending on a concrete cache type.
+type eventedBlockCache interface {
+	SetOnEvent(func(CacheEvent))
+}
+
 // RoundTrip implements http.RoundTripper with transparent block-aligned caching.
 func (t *CachedBlockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if t.Transport == nil {
@@ -82,6 +162,14 @@ func (t *CachedBlockTransport) RoundTrip(req *http.Request) (*http.Response, err
 	}
 	bs := t.BlockSize
 
+	if t.OnCacheEvent != nil {
+		t.cacheEventOnce.Do(func() {
+			if ec, ok := t.Cache.(eventedBlockCache); ok {
+				ec.SetOnEvent(t.OnCacheEvent)
+			}
+		})
+	}
+
 	if req.Method != http.MethodGet {
 		return t.Transport.RoundTrip(req)
 	}
@@ -109,66 +197,75 @@ func (t *CachedBlockTransport) RoundTrip(req *http.Request) (*http.Response, err
 		blockNum := b / bs
 		if t.Cache == nil {
 			missing = append(missing, blockNum)
+			atomic.AddInt64(&t.MissCount, 1)
 			continue
 		}
 		if _, ok := t.Cache.Get(blockNum); !ok {
 			missing = append(missing, blockNum)
+			atomic.AddInt64(&t.MissCount, 1)
+		} else {
+			atomic.AddInt64(&t.HitCount, 1)
 		}
 	}
 
-	// Fetch all missing blocks in one contiguous request if needed
-	if len(missing) > 0 {
-		firstBlock := missing[0]
-		lastBlock := missing[len(missing)-1]
-		key := strconv.FormatInt(firstBlock, 10)
-
-		_, err, _ = t.group.Do(key, func() (any, error) {
-			rangeStart := firstBlock * bs
-			rangeEnd := (lastBlock+1)*bs - 1
+	// A 412 during the fetch below invalidates every cached block for this
+	// URL, not just the run that triggered it (see fetchRange); stash a
+	// flag on req's context so we can tell afterward.
+	var invalidated int32
+	req = req.WithContext(withInvalidationFlag(req.Context(), &invalidated))
 
-			newReq := req.Clone(req.Context())
-			newReq.Header = req.Header.Clone()
-			newReq.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", rangeStart, rangeEnd))
-			logRequest(newReq)
+	// Fetch the missing blocks, coalescing adjacent ones into runs and
+	// batching runs into as few multi-range requests as possible.
+	if len(missing) > 0 {
+		noStore, err := t.fetchMissing(req, bs, coalesceBlockRuns(missing))
+		if err != nil {
+			return nil, err
+		}
+		if len(noStore) > 0 {
+			// Cache-Control: no-store or Vary: * means this data must not
+			// be reused for other requests; keep it in Cache just long
+			// enough to assemble this response below, then drop it.
+			defer func() {
+				for _, b := range noStore {
+					t.Cache.Delete(b)
+				}
+			}()
+		}
+	}
 
-			resp, err := t.Transport.RoundTrip(newReq)
-			if err != nil {
-				return nil, err
+	if atomic.LoadInt32(&invalidated) != 0 {
+		// Every block tracked for this URL was just dropped, including ones
+		// that were cache hits above and so were never in missing; refetch
+		// the full requested range rather than returning a body that's
+		// silently missing the blocks that got invalidated out from under it.
+		var revalidated []int64
+		for b := blockStart; b <= blockEnd; b += bs {
+			blockNum := b / bs
+			if t.Cache == nil {
+				revalidated = append(revalidated, blockNum)
+				continue
 			}
-			defer resp.Body.Close()
-
-			logResponse(resp)
-
-			if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
-				return nil, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+			if _, ok := t.Cache.Get(blockNum); !ok {
+				revalidated = append(revalidated, blockNum)
 			}
-
-			body, err := io.ReadAll(resp.Body)
+		}
+		if len(revalidated) > 0 {
+			noStore, err := t.fetchMissing(req, bs, coalesceBlockRuns(revalidated))
 			if err != nil {
 				return nil, err
 			}
-
-			// Split and populate cache
-			for i, b := range missing {
-				offset := int64(i) * bs
-				if offset >= int64(len(body)) {
-					break
-				}
-				end := offset + bs
-				if end > int64(len(body)) {
-					end = int64(len(body))
-				}
-				if t.Cache != nil {
-					t.Cache.Put(b, body[offset:end])
-				}
+			if len(noStore) > 0 {
+				defer func() {
+					for _, b := range noStore {
+						t.Cache.Delete(b)
+					}
+				}()
 			}
-			return nil, nil
-		})
-		if err != nil {
-			return nil, err
 		}
 	}
 
+	t.maybePrefetch(req, bs, blockStart, blockEnd)
+
 	// Rebuild combined body in logical block order
 	combined := make([]byte, 0, int(numBlocks*bs))
 	for b := blockStart; b <= blockEnd; b += bs {
@@ -209,3 +306,414 @@ func (t *CachedBlockTransport) RoundTrip(req *http.Request) (*http.Response, err
 	}
 	return resp, nil
 }
+
+// fetchRange issues a Range GET for [start, end], retrying per t.Retry on
+// network errors, non-2xx/206 responses, and 206 bodies shorter than the
+// requested range. A nil t.Retry disables retries and the first failure is
+// returned as-is. A 412 Precondition Failed (the resource changed since its
+// validators were first recorded) is handled separately: the stale cache
+// state for this URL is dropped and the fetch is retried exactly once
+// against fresh validators, outside of the t.Retry backoff loop. The
+// returned bool reports whether the body may be cached for reuse by other
+// requests; it is false when the response carries Cache-Control: no-store
+// or Vary: *.
+func (t *CachedBlockTransport) fetchRange(req *http.Request, start, end int64) ([]byte, bool, error) {
+	want := end - start + 1
+	retriedPrecondition := false
+	for attempt := 0; ; attempt++ {
+		body, cacheable, err := t.doFetchRange(req, start, end, want)
+		if err == nil {
+			return body, cacheable, nil
+		}
+		if err == errPreconditionFailed {
+			if retriedPrecondition {
+				return nil, false, err
+			}
+			retriedPrecondition = true
+			url := req.URL.String()
+			t.invalidateURL(url)
+			t.urlMeta.Delete(url)
+			markInvalidated(req.Context())
+			continue
+		}
+		if !t.Retry.shouldRetry(attempt, err) {
+			return nil, false, err
+		}
+		if werr := t.Retry.wait(req.Context(), attempt, retryAfter(err)); werr != nil {
+			return nil, false, werr
+		}
+	}
+}
+
+func (t *CachedBlockTransport) doFetchRange(req *http.Request, start, end, want int64) ([]byte, bool, error) {
+	url := req.URL.String()
+
+	newReq := req.Clone(req.Context())
+	newReq.Header = req.Header.Clone()
+	newReq.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	if meta, ok := t.validatorsFor(url); ok {
+		meta.ApplyValidators(newReq.Header)
+	}
+	reqStart := logRequest(newReq)
+
+	resp, err := t.Transport.RoundTrip(newReq)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	logResponse(resp, reqStart)
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return nil, false, errPreconditionFailed
+	}
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return nil, false, &statusError{resp: resp}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		// Prefer what the server actually declares it sent over what we
+		// asked for: a final, shorter-than-requested block at EOF is
+		// legitimate, not a truncation.
+		expect := want
+		if rs, re, ok := parseContentRange(resp.Header.Get("Content-Range")); ok {
+			expect = re - rs + 1
+		} else if resp.ContentLength >= 0 {
+			expect = resp.ContentLength
+		}
+		if int64(len(body)) < expect {
+			return nil, false, io.ErrUnexpectedEOF
+		}
+	}
+
+	t.recordValidators(url, extractMetadata(resp.Header))
+	return body, !bypassesCache(resp.Header), nil
+}
+
+// invalidationFlagKey is the context key fetchRange uses to report back to
+// RoundTrip that a 412 invalidated the whole URL's cached blocks, not just
+// the run it was fetching.
+type invalidationFlagKey struct{}
+
+// withInvalidationFlag returns a copy of ctx carrying flag, which fetchRange
+// sets (atomically) if it invalidates the URL after a 412.
+func withInvalidationFlag(ctx context.Context, flag *int32) context.Context {
+	return context.WithValue(ctx, invalidationFlagKey{}, flag)
+}
+
+// markInvalidated sets the flag stashed in ctx by withInvalidationFlag, if
+// any; direct callers of fetchRange outside of RoundTrip (e.g. tests) won't
+// have one set, which is fine since there's no wider range to reconcile.
+func markInvalidated(ctx context.Context) {
+	if flag, ok := ctx.Value(invalidationFlagKey{}).(*int32); ok {
+		atomic.StoreInt32(flag, 1)
+	}
+}
+
+// blockRun is a contiguous run of missing block numbers (inclusive).
+type blockRun struct {
+	start, end int64
+}
+
+// coalesceBlockRuns folds a sorted slice of block numbers into contiguous
+// runs, so a wide request with only a few missing blocks interspersed among
+// cached ones doesn't re-download the blocks already resident.
+func coalesceBlockRuns(blocks []int64) []blockRun {
+	if len(blocks) == 0 {
+		return nil
+	}
+	runs := []blockRun{{blocks[0], blocks[0]}}
+	for _, b := range blocks[1:] {
+		last := &runs[len(runs)-1]
+		if b == last.end+1 {
+			last.end = b
+			continue
+		}
+		runs = append(runs, blockRun{b, b})
+	}
+	return runs
+}
+
+// fetchMissing fetches runs, batching up to MaxRangesPerRequest runs into a
+// single multi-range request at a time. Each batch is deduplicated via
+// singleflight so concurrent callers asking for the same missing runs share
+// one fetch. It returns the block numbers fetched from a no-store/Vary: *
+// response, which the caller must evict from Cache once it's done reading
+// them back.
+func (t *CachedBlockTransport) fetchMissing(req *http.Request, bs int64, runs []blockRun) ([]int64, error) {
+	maxRanges := t.MaxRangesPerRequest
+	if maxRanges <= 0 {
+		maxRanges = defaultMaxRangesPerRequest
+	}
+
+	var noStore []int64
+	for len(runs) > 0 {
+		n := len(runs)
+		if n > maxRanges {
+			n = maxRanges
+		}
+		batch := runs[:n]
+		runs = runs[n:]
+
+		v, err, shared := t.group.Do(blockRunsKey(batch), func() (any, error) {
+			return t.fetchBlockRuns(req, bs, batch)
+		})
+		if shared {
+			atomic.AddInt64(&t.CoalescedCount, 1)
+		}
+		if err != nil {
+			return noStore, err
+		}
+		if v != nil {
+			noStore = append(noStore, v.([]int64)...)
+		}
+	}
+	return noStore, nil
+}
+
+// blockRunsKey builds a singleflight key identifying a batch of runs.
+func blockRunsKey(runs []blockRun) string {
+	var b strings.Builder
+	for i, r := range runs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%d-%d", r.start, r.end)
+	}
+	return b.String()
+}
+
+// fetchBlockRuns fetches one or more block runs in as few round trips as
+// possible. A single run uses the existing single-range fast path; more
+// than one issues a comma-joined Range request and expects a
+// multipart/byteranges response (as net/http/fs.go serves one), falling
+// back to one request per run if the server collapses the ranges into a
+// 200 or a single-part 206, or ignores the multi-range request otherwise.
+// Unlike fetchAndStore, this opportunistic multi-range attempt is not
+// retried; a failure here falls back to the sequential, retried path. It
+// returns the block numbers stored from a no-store/Vary: * response, if
+// any.
+func (t *CachedBlockTransport) fetchBlockRuns(req *http.Request, bs int64, runs []blockRun) ([]int64, error) {
+	if len(runs) == 1 {
+		return t.fetchAndStore(req, bs, runs[0])
+	}
+
+	parts := make([]string, len(runs))
+	for i, rn := range runs {
+		parts[i] = fmt.Sprintf("%d-%d", rn.start*bs, (rn.end+1)*bs-1)
+	}
+
+	url := req.URL.String()
+	newReq := req.Clone(req.Context())
+	newReq.Header = req.Header.Clone()
+	newReq.Header.Set("Range", "bytes="+strings.Join(parts, ","))
+	if meta, ok := t.validatorsFor(url); ok {
+		meta.ApplyValidators(newReq.Header)
+	}
+	start := logRequest(newReq)
+
+	resp, err := t.Transport.RoundTrip(newReq)
+	if err != nil {
+		return t.fetchRunsSequential(req, bs, runs)
+	}
+	defer resp.Body.Close()
+
+	logResponse(resp, start)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Server ignored the Range header entirely; fall back.
+		return t.fetchRunsSequential(req, bs, runs)
+	case http.StatusPartialContent:
+	default:
+		// Includes 412 Precondition Failed; the sequential fallback goes
+		// through fetchRange, which invalidates and retries once.
+		return t.fetchRunsSequential(req, bs, runs)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		// A single-part 206; the server collapsed the ranges.
+		return t.fetchRunsSequential(req, bs, runs)
+	}
+
+	t.recordValidators(url, extractMetadata(resp.Header))
+	cacheable := !bypassesCache(resp.Header)
+
+	found := make([]bool, len(runs))
+	var noStore []int64
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return noStore, fmt.Errorf("httpseek: reading multipart byteranges: %w", err)
+		}
+
+		partStart, _, ok := parseContentRange(part.Header.Get("Content-Range"))
+		if !ok {
+			part.Close()
+			continue
+		}
+		data, err := io.ReadAll(part)
+		part.Close()
+		if err != nil {
+			return noStore, err
+		}
+
+		for i, rn := range runs {
+			if !found[i] && partStart == rn.start*bs {
+				stored := t.storeRun(url, rn, data, bs)
+				if !cacheable {
+					noStore = append(noStore, stored...)
+				}
+				found[i] = true
+				break
+			}
+		}
+	}
+
+	// Some servers reorder or drop parts; fetch whatever didn't show up.
+	var missed []blockRun
+	for i, ok := range found {
+		if !ok {
+			missed = append(missed, runs[i])
+		}
+	}
+	if len(missed) > 0 {
+		ns, err := t.fetchRunsSequential(req, bs, missed)
+		noStore = append(noStore, ns...)
+		return noStore, err
+	}
+	return noStore, nil
+}
+
+// fetchRunsSequential fetches each run with its own retried fetchAndStore
+// call; used when a multi-range request isn't honored.
+func (t *CachedBlockTransport) fetchRunsSequential(req *http.Request, bs int64, runs []blockRun) ([]int64, error) {
+	var noStore []int64
+	for _, run := range runs {
+		ns, err := t.fetchAndStore(req, bs, run)
+		noStore = append(noStore, ns...)
+		if err != nil {
+			return noStore, err
+		}
+	}
+	return noStore, nil
+}
+
+// fetchAndStore fetches run and populates the cache, splitting it into up
+// to Parallelism concurrent sub-fetches when Parallelism > 1. Each sub-fetch
+// is deduplicated via singleflight on its own block-range key, so two
+// callers racing on the same sub-run share one fetch rather than issuing it
+// twice.
+func (t *CachedBlockTransport) fetchAndStore(req *http.Request, bs int64, run blockRun) ([]int64, error) {
+	if t.Parallelism <= 1 {
+		return t.fetchRunDirect(req, bs, run)
+	}
+	subRuns := splitBlockRun(run, t.Parallelism)
+	if len(subRuns) <= 1 {
+		return t.fetchRunDirect(req, bs, run)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(subRuns))
+	noStores := make([][]int64, len(subRuns))
+	for i, sr := range subRuns {
+		wg.Add(1)
+		go func(i int, sr blockRun) {
+			defer wg.Done()
+			v, err, _ := t.group.Do(blockRunsKey([]blockRun{sr}), func() (any, error) {
+				return t.fetchRunDirect(req, bs, sr)
+			})
+			errs[i] = err
+			if v != nil {
+				noStores[i] = v.([]int64)
+			}
+		}(i, sr)
+	}
+	wg.Wait()
+
+	var noStore []int64
+	for i, err := range errs {
+		if err != nil {
+			return noStore, err
+		}
+		noStore = append(noStore, noStores[i]...)
+	}
+	return noStore, nil
+}
+
+// fetchRunDirect fetches run with fetchRange (retried per t.Retry) and
+// splits the body across the cache in bs-sized blocks. It returns run's
+// block numbers if the response may not be cached for reuse.
+func (t *CachedBlockTransport) fetchRunDirect(req *http.Request, bs int64, run blockRun) ([]int64, error) {
+	body, cacheable, err := t.fetchRange(req, run.start*bs, (run.end+1)*bs-1)
+	if err != nil {
+		return nil, err
+	}
+	stored := t.storeRun(req.URL.String(), run, body, bs)
+	if cacheable {
+		return nil, nil
+	}
+	return stored, nil
+}
+
+// splitBlockRun divides run into up to n contiguous sub-runs of roughly
+// equal size, for parallel fetching. It never returns more sub-runs than
+// run has blocks, and returns a single sub-run covering the whole of run
+// when n <= 1.
+func splitBlockRun(run blockRun, n int) []blockRun {
+	total := run.end - run.start + 1
+	if n <= 1 || total <= 1 {
+		return []blockRun{run}
+	}
+	if int64(n) > total {
+		n = int(total)
+	}
+
+	runs := make([]blockRun, 0, n)
+	chunk := total / int64(n)
+	rem := total % int64(n)
+	start := run.start
+	for i := 0; i < n; i++ {
+		size := chunk
+		if int64(i) < rem {
+			size++
+		}
+		runs = append(runs, blockRun{start, start + size - 1})
+		start += size
+	}
+	return runs
+}
+
+// storeRun splits body (as fetched for run) into bs-sized blocks and
+// populates the cache, tracking each stored block against url so it can be
+// invalidated later. body may be short at EOF. It returns the block numbers
+// actually stored.
+func (t *CachedBlockTransport) storeRun(url string, run blockRun, body []byte, bs int64) []int64 {
+	if t.Cache == nil {
+		return nil
+	}
+	var stored []int64
+	for b := run.start; b <= run.end; b++ {
+		offset := (b - run.start) * bs
+		if offset >= int64(len(body)) {
+			break
+		}
+		end := offset + bs
+		if end > int64(len(body)) {
+			end = int64(len(body))
+		}
+		t.Cache.Put(b, body[offset:end])
+		t.trackBlock(url, b)
+		stored = append(stored, b)
+	}
+	return stored
+}
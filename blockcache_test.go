@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -464,6 +465,135 @@ func TestCachedBlockTransport_ErrorsDoNotCache(t *testing.T) {
 	}
 }
 
+func TestCachedBlockTransport_MultiRangeSkipsCachedBlocks(t *testing.T) {
+	data := make([]byte, 1536)
+	for i := range data {
+		data[i] = byte('A' + (i % 26))
+	}
+	srv := serveMultipartRanges(data)
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	cache.Put(1, data[512:1024]) // pre-warm the middle block
+
+	var hits int64
+	tr := &CachedBlockTransport{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			atomic.AddInt64(&hits, 1)
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+		Cache:     cache,
+		BlockSize: 512,
+	}
+	client := &http.Client{Transport: tr}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-1535")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !bytes.Equal(body, data) {
+		t.Fatalf("got %d bytes, want %d matching the original data", len(body), len(data))
+	}
+	if atomic.LoadInt64(&hits) != 1 {
+		t.Fatalf("expected a single multi-range request, got %d", atomic.LoadInt64(&hits))
+	}
+}
+
+func TestCachedBlockTransport_MultiRangeFallsBackOnCollapsedResponse(t *testing.T) {
+	data := make([]byte, 1536)
+	for i := range data {
+		data[i] = byte('A' + (i % 26))
+	}
+
+	// A server that doesn't support multi-range and falls back to
+	// returning the whole body with 200, but otherwise honors Range.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHdr := strings.TrimPrefix(r.Header.Get("Range"), "bytes=")
+		if strings.Contains(rangeHdr, ",") {
+			w.WriteHeader(http.StatusOK)
+			w.Write(data)
+			return
+		}
+		var start, end int
+		fmt.Sscanf(rangeHdr, "%d-%d", &start, &end)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+	}))
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	cache.Put(1, data[512:1024])
+
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     cache,
+			BlockSize: 512,
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-1535")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !bytes.Equal(body, data) {
+		t.Fatalf("got %d bytes, want %d matching the original data", len(body), len(data))
+	}
+	if _, ok := cache.Get(0); !ok {
+		t.Fatal("expected block 0 to be cached via the sequential fallback")
+	}
+	if _, ok := cache.Get(2); !ok {
+		t.Fatal("expected block 2 to be cached via the sequential fallback")
+	}
+}
+
+func TestCachedBlockTransport_ParallelismSplitsRunIntoConcurrentFetches(t *testing.T) {
+	srv, hitCount := newBlockServer()
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport:   http.DefaultTransport,
+			Cache:       cache,
+			BlockSize:   512,
+			Parallelism: 4,
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-2047") // spans blocks 0-3, all missing
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if len(body) != 2048 {
+		t.Fatalf("got %d bytes, want 2048", len(body))
+	}
+	if got := atomic.LoadInt64(hitCount); got != 4 {
+		t.Fatalf("expected 4 concurrent single-block fetches, got %d", got)
+	}
+	for b := int64(0); b < 4; b++ {
+		if _, ok := cache.Get(b); !ok {
+			t.Fatalf("expected block %d to be cached", b)
+		}
+	}
+}
+
 func TestMemoryBlockCache_BasicOps(t *testing.T) {
 	cache := NewMemoryBlockCache()
 	data := []byte("hello")
@@ -0,0 +1,11 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+// defaultMaxRangesPerRequest caps how many byte ranges go into a single
+// Range header when neither CachedBlockTransport nor UffdHTTPReader
+// override it; some servers refuse a multipart/byteranges response with
+// too many parts. It lives in its own platform-neutral file (rather than
+// alongside UffdHTTPReader's other defaults in uffd_common.go) because
+// blockcache.go, which also uses it, has no unix build constraint.
+const defaultMaxRangesPerRequest = 8
@@ -0,0 +1,119 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// noReadAheadKey is the context key set by WithNoReadAhead.
+type noReadAheadKey struct{}
+
+// WithNoReadAhead returns a copy of ctx that disables CachedBlockTransport's
+// background read-ahead prefetch for requests made with it, regardless of
+// the transport's ReadAhead setting. Use it for callers doing known
+// random-access reads, where prefetched blocks would just waste bandwidth.
+func WithNoReadAhead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noReadAheadKey{}, true)
+}
+
+// maybePrefetch detects sequential access to req.URL and, if this request
+// starts in the block right after the one the previous request for the same
+// URL ended in, kicks off background fetches for the next ReadAhead blocks. A single non-sequential
+// (random-looking) access resets the detector, so random-access workloads
+// never trigger prefetch.
+func (t *CachedBlockTransport) maybePrefetch(req *http.Request, bs, blockStart, blockEnd int64) {
+	if t.ReadAhead <= 0 {
+		return
+	}
+	if req.Context().Value(noReadAheadKey{}) != nil {
+		return
+	}
+
+	key := req.URL.String()
+	nextBlock := blockEnd/bs + 1
+
+	t.mu.Lock()
+	if t.nextSeq == nil {
+		t.nextSeq = make(map[string]int64)
+	}
+	expected, sequential := t.nextSeq[key]
+	sequential = sequential && expected == blockStart/bs
+	t.nextSeq[key] = nextBlock
+	t.mu.Unlock()
+
+	if !sequential {
+		return
+	}
+
+	t.prefetchOnce.Do(func() {
+		n := t.PrefetchWorkers
+		if n <= 0 {
+			n = defaultPrefetchWorkers
+		}
+		t.prefetchSem = make(chan struct{}, n)
+	})
+
+	for i := int64(0); i < int64(t.ReadAhead); i++ {
+		block := nextBlock + i
+		if t.Cache != nil {
+			if _, ok := t.Cache.Get(block); ok {
+				continue
+			}
+		}
+		select {
+		case t.prefetchSem <- struct{}{}:
+		default:
+			// Worker pool saturated; stop prefetching for this round rather
+			// than stampeding the origin.
+			return
+		}
+		go func(block int64) {
+			defer func() { <-t.prefetchSem }()
+			t.prefetchBlock(req, block, bs)
+		}(block)
+	}
+}
+
+// prefetchBlock speculatively fetches a single block and populates the
+// cache. Like the foreground path, it sends the URL's recorded validators
+// if any are known; a 412 here just means the speculative fetch is
+// discarded rather than triggering the foreground invalidate-and-retry.
+func (t *CachedBlockTransport) prefetchBlock(req *http.Request, block, bs int64) {
+	url := req.URL.String()
+
+	newReq := req.Clone(req.Context())
+	newReq.Header = req.Header.Clone()
+	newReq.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", block*bs, (block+1)*bs-1))
+	if meta, ok := t.validatorsFor(url); ok {
+		meta.ApplyValidators(newReq.Header)
+	}
+	start := logRequest(newReq)
+
+	resp, err := t.Transport.RoundTrip(newReq)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	logResponse(resp, start)
+
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return
+	}
+	if bypassesCache(resp.Header) {
+		return
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || t.Cache == nil {
+		return
+	}
+	t.Cache.Put(block, body)
+	t.trackBlock(url, block)
+	atomic.AddInt64(&t.PrefetchCount, 1)
+}
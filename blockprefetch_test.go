@@ -0,0 +1,145 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedBlockTransport_PrefetchOnSequentialAccess(t *testing.T) {
+	srv, _ := newBlockServer()
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	tr := &CachedBlockTransport{
+		Transport: http.DefaultTransport,
+		Cache:     cache,
+		BlockSize: 512,
+		ReadAhead: 2,
+	}
+	client := &http.Client{Transport: tr}
+
+	for _, rng := range []string{"bytes=0-511", "bytes=512-1023"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Range", rng)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// Prefetch runs in the background; give it a moment to land.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := cache.Get(2); ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, ok := cache.Get(2); !ok {
+		t.Fatal("expected block 2 to be prefetched after two sequential reads")
+	}
+	if atomic.LoadInt64(&tr.PrefetchCount) == 0 {
+		t.Fatal("expected PrefetchCount > 0")
+	}
+}
+
+func TestCachedBlockTransport_NoPrefetchOnRandomAccess(t *testing.T) {
+	srv, _ := newBlockServer()
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	tr := &CachedBlockTransport{
+		Transport: http.DefaultTransport,
+		Cache:     cache,
+		BlockSize: 512,
+		ReadAhead: 2,
+	}
+	client := &http.Client{Transport: tr}
+
+	for _, rng := range []string{"bytes=0-511", "bytes=2048-2559"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Range", rng)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt64(&tr.PrefetchCount) != 0 {
+		t.Fatalf("expected no prefetch for random access, got %d", tr.PrefetchCount)
+	}
+}
+
+func TestCachedBlockTransport_WithNoReadAheadDisablesPrefetch(t *testing.T) {
+	srv, _ := newBlockServer()
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	tr := &CachedBlockTransport{
+		Transport: http.DefaultTransport,
+		Cache:     cache,
+		BlockSize: 512,
+		ReadAhead: 2,
+	}
+	client := &http.Client{Transport: tr}
+
+	ctx := WithNoReadAhead(context.Background())
+	for _, rng := range []string{"bytes=0-511", "bytes=512-1023"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req = req.WithContext(ctx)
+		req.Header.Set("Range", rng)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt64(&tr.PrefetchCount) != 0 {
+		t.Fatalf("expected WithNoReadAhead to suppress prefetch, got %d", tr.PrefetchCount)
+	}
+}
+
+func TestCachedBlockTransport_HitMissCounters(t *testing.T) {
+	srv, _ := newBlockServer()
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	tr := &CachedBlockTransport{
+		Transport: http.DefaultTransport,
+		Cache:     cache,
+		BlockSize: 512,
+	}
+	client := &http.Client{Transport: tr}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-511")
+	resp, _ := client.Do(req)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	resp, _ = client.Do(req)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if atomic.LoadInt64(&tr.MissCount) != 1 {
+		t.Fatalf("expected 1 miss, got %d", tr.MissCount)
+	}
+	if atomic.LoadInt64(&tr.HitCount) != 1 {
+		t.Fatalf("expected 1 hit, got %d", tr.HitCount)
+	}
+}
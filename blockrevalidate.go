@@ -0,0 +1,81 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// errPreconditionFailed signals that a block fetch got a 412 Precondition
+// Failed against the URL's recorded validators. fetchRange handles it by
+// invalidating the URL's cached state and retrying once.
+var errPreconditionFailed = errors.New("httpseek: precondition failed")
+
+// validatorsFor returns the first-seen Metadata recorded for url, if any.
+func (t *CachedBlockTransport) validatorsFor(url string) (Metadata, bool) {
+	v, ok := t.urlMeta.Load(url)
+	if !ok {
+		return Metadata{}, false
+	}
+	return v.(Metadata), true
+}
+
+// recordValidators remembers meta as url's validators, if none are recorded
+// yet. The first response for a URL wins; it's cleared (via urlMeta.Delete)
+// rather than overwritten when the resource is found to have changed.
+func (t *CachedBlockTransport) recordValidators(url string, meta Metadata) {
+	t.urlMeta.LoadOrStore(url, meta)
+}
+
+// trackBlock records that block was cached for url under its current
+// validators, so invalidateURL can undo it if the resource changes.
+func (t *CachedBlockTransport) trackBlock(url string, block int64) {
+	t.urlBlocksMu.Lock()
+	defer t.urlBlocksMu.Unlock()
+	if t.urlBlocks == nil {
+		t.urlBlocks = make(map[string]map[int64]struct{})
+	}
+	blocks, ok := t.urlBlocks[url]
+	if !ok {
+		blocks = make(map[int64]struct{})
+		t.urlBlocks[url] = blocks
+	}
+	blocks[block] = struct{}{}
+}
+
+// invalidateURL deletes every block tracked for url from the cache, so a
+// Precondition Failed response doesn't leave stale blocks mixed in with the
+// fresh ones fetched by the retry in fetchRange.
+func (t *CachedBlockTransport) invalidateURL(url string) {
+	t.urlBlocksMu.Lock()
+	blocks := t.urlBlocks[url]
+	delete(t.urlBlocks, url)
+	t.urlBlocksMu.Unlock()
+
+	if t.Cache == nil {
+		return
+	}
+	for block := range blocks {
+		t.Cache.Delete(block)
+	}
+}
+
+// bypassesCache reports whether resp's headers mean its body must not be
+// stored in the block cache for reuse by other requests: an explicit
+// Cache-Control: no-store, or Vary: * (the response depends on request
+// headers the block cache key doesn't account for).
+func bypassesCache(h http.Header) bool {
+	if h.Get("Vary") == "*" {
+		return true
+	}
+	for _, cc := range h.Values("Cache-Control") {
+		for _, dir := range strings.Split(cc, ",") {
+			if strings.EqualFold(strings.TrimSpace(dir), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
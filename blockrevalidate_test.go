@@ -0,0 +1,227 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// conditionalBlockServer serves Range requests against data with an ETag
+// that flips to "v2" once at least changeAfter requests have already been
+// served, honoring If-Match the way a real origin would: a non-matching
+// If-Match gets a 412 instead of the range.
+func conditionalBlockServer(data []byte, changeAfter int64) *httptest.Server {
+	var reqs int64
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"v1"`
+		if atomic.LoadInt64(&reqs) >= changeAfter {
+			etag = `"v2"`
+		}
+		atomic.AddInt64(&reqs, 1)
+
+		if im := r.Header.Get("If-Match"); im != "" && im != etag {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		var start, end int
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+	}))
+}
+
+func TestCachedBlockTransport_RevalidatesOn412(t *testing.T) {
+	data := make([]byte, 1024)
+	for i := range data {
+		data[i] = byte('A' + (i % 26))
+	}
+	srv := conditionalBlockServer(data, 1)
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     cache,
+			BlockSize: 512,
+		},
+	}
+
+	req1, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req1.Header.Set("Range", "bytes=0-511")
+	resp1, err := client.Do(req1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body1, _ := io.ReadAll(resp1.Body)
+	resp1.Body.Close()
+	if !bytes.Equal(body1, data[0:512]) {
+		t.Fatalf("unexpected first body")
+	}
+
+	// The server's ETag has since flipped to "v2", so a conditional fetch
+	// for block 0 now gets a 412. That must be handled transparently:
+	// invalidate and retry, not surfaced as an error.
+	cache.Delete(0)
+	req2, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req2.Header.Set("Range", "bytes=0-511")
+	resp2, err := client.Do(req2)
+	if err != nil {
+		t.Fatalf("expected transparent revalidation, got error: %v", err)
+	}
+	body2, _ := io.ReadAll(resp2.Body)
+	resp2.Body.Close()
+	if !bytes.Equal(body2, data[0:512]) {
+		t.Fatalf("got %q, want the block bytes", body2)
+	}
+}
+
+func TestCachedBlockTransport_412InvalidatesOtherCachedBlocksForURL(t *testing.T) {
+	data := make([]byte, 1536)
+	for i := range data {
+		data[i] = byte('A' + (i % 26))
+	}
+	srv := conditionalBlockServer(data, 2)
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     cache,
+			BlockSize: 512,
+		},
+	}
+
+	// Warm blocks 0 and 1 while the server is still on "v1".
+	for _, rng := range []string{"bytes=0-511", "bytes=512-1023"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Range", rng)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if _, ok := cache.Get(1); !ok {
+		t.Fatal("expected block 1 to be cached before the resource changes")
+	}
+
+	// Block 2 is still missing once the server has moved on to "v2": this
+	// fetch gets a 412, which should invalidate blocks 0 and 1 too, since
+	// they're stale under the new validators.
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=1024-1535")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("expected transparent revalidation, got error: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if _, ok := cache.Get(1); ok {
+		t.Fatal("expected block 1 to be invalidated after the resource changed")
+	}
+}
+
+func TestCachedBlockTransport_412DuringMixedRequestRefetchesWholeRange(t *testing.T) {
+	data := make([]byte, 1536)
+	for i := range data {
+		data[i] = byte('A' + (i % 26))
+	}
+	srv := conditionalBlockServer(data, 2)
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     cache,
+			BlockSize: 512,
+		},
+	}
+
+	// Warm blocks 0 and 1 while the server is still on "v1".
+	for _, rng := range []string{"bytes=0-511", "bytes=512-1023"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Range", rng)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// A single request spanning the two cached blocks plus the still-missing
+	// block 2 gets a 412 fetching block 2, once the server has moved on to
+	// "v2". That invalidates blocks 0 and 1 along with it, so the response
+	// must still come back whole rather than missing the blocks that were
+	// cache hits going in.
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-1535")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("expected transparent revalidation, got error: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, data) {
+		t.Fatalf("got %d bytes, want %d: body truncated by the precondition-retry path", len(body), len(data))
+	}
+}
+
+func TestCachedBlockTransport_NoStoreResponseIsNotCached(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 32) // 512 bytes
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var start, end int
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+		if end >= len(data) {
+			end = len(data) - 1
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+	}))
+	defer srv.Close()
+
+	cache := NewMemoryBlockCache()
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     cache,
+			BlockSize: 512,
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-7")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !bytes.Equal(body, data[0:8]) {
+		t.Fatalf("got %q, want %q", body, data[0:8])
+	}
+	if _, ok := cache.Get(0); ok {
+		t.Fatal("expected Cache-Control: no-store response not to be cached")
+	}
+}
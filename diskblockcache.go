@@ -0,0 +1,262 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DiskBlockCache is a BlockCache that stores each block as a file under dir,
+// evicting the least-recently-used blocks once the total size on disk
+// exceeds maxBytes. It is safe for concurrent use and durable across
+// process restarts: NewDiskBlockCache rebuilds the LRU order from an
+// index.json sidecar, falling back to scanning dir (oldest mtime first)
+// if the sidecar is missing or out of sync with what's actually on disk.
+//
+// Callers that need the cache scoped to a specific resource version
+// (so a stale block from a previous ETag is never served) should use a
+// dedicated dir per URL+validators.
+type DiskBlockCache struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	lru   *list.List              // front = most recently used
+	elems map[int64]*list.Element // block -> element holding block number
+	size  int64
+}
+
+type diskCacheEntry struct {
+	Block int64 `json:"block"`
+	Size  int64 `json:"size"`
+}
+
+var _ BlockCache = (*DiskBlockCache)(nil)
+
+// NewDiskBlockCache opens (or creates) a disk-backed block cache rooted at dir.
+// maxBytes <= 0 means unbounded.
+func NewDiskBlockCache(dir string, maxBytes int64) (*DiskBlockCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &DiskBlockCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		elems:    make(map[int64]*list.Element),
+	}
+	if err := c.rebuildIndex(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *DiskBlockCache) blockPath(block int64) string {
+	return filepath.Join(c.dir, fmt.Sprintf("block-%020d.bin", block))
+}
+
+func (c *DiskBlockCache) indexPath() string {
+	return filepath.Join(c.dir, "index.json")
+}
+
+// rebuildIndex restores LRU order on open. It trusts the index.json sidecar
+// only if every block it references still exists on disk; otherwise it
+// rescans dir and orders entries by mtime, oldest (least-recently-used) first.
+func (c *DiskBlockCache) rebuildIndex() error {
+	if data, err := os.ReadFile(c.indexPath()); err == nil {
+		var entries []diskCacheEntry
+		if json.Unmarshal(data, &entries) == nil && c.indexMatchesDisk(entries) {
+			// writeIndex lists entries most-recently-used first.
+			for _, e := range entries {
+				elem := c.lru.PushBack(e.Block)
+				c.elems[e.Block] = elem
+				c.size += e.Size
+			}
+			return nil
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(c.dir, "block-*.bin"))
+	if err != nil {
+		return err
+	}
+	type scanned struct {
+		block   int64
+		size    int64
+		modTime time.Time
+	}
+	found := make([]scanned, 0, len(matches))
+	for _, p := range matches {
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		var block int64
+		if _, err := fmt.Sscanf(filepath.Base(p), "block-%020d.bin", &block); err != nil {
+			continue
+		}
+		found = append(found, scanned{block, fi.Size(), fi.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.Before(found[j].modTime) })
+	for _, f := range found {
+		elem := c.lru.PushFront(f.block)
+		c.elems[f.block] = elem
+		c.size += f.size
+	}
+	return nil
+}
+
+func (c *DiskBlockCache) indexMatchesDisk(entries []diskCacheEntry) bool {
+	for _, e := range entries {
+		if _, err := os.Stat(c.blockPath(e.Block)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the block data if present.
+func (c *DiskBlockCache) Get(block int64) ([]byte, bool) {
+	c.mu.Lock()
+	elem, ok := c.elems[block]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.blockPath(block))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put writes data for block to disk and evicts least-recently-used blocks
+// until the cache fits within maxBytes.
+func (c *DiskBlockCache) Put(block int64, data []byte) {
+	path := c.blockPath(block)
+	var oldSize int64
+	if fi, err := os.Stat(path); err == nil {
+		oldSize = fi.Size()
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.elems[block]; ok {
+		c.lru.MoveToFront(elem)
+		c.size -= oldSize
+	} else {
+		elem := c.lru.PushFront(block)
+		c.elems[block] = elem
+	}
+	c.size += int64(len(data))
+	c.evictLocked()
+	c.mu.Unlock()
+
+	c.persistIndex()
+}
+
+// evictLocked removes least-recently-used blocks until the cache is within
+// budget. c.mu must be held.
+func (c *DiskBlockCache) evictLocked() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	for c.size > c.maxBytes {
+		back := c.lru.Back()
+		if back == nil {
+			return
+		}
+		block := back.Value.(int64)
+		if fi, err := os.Stat(c.blockPath(block)); err == nil {
+			c.size -= fi.Size()
+		}
+		os.Remove(c.blockPath(block))
+		c.lru.Remove(back)
+		delete(c.elems, block)
+	}
+}
+
+// Delete removes a single block.
+func (c *DiskBlockCache) Delete(block int64) {
+	c.mu.Lock()
+	if elem, ok := c.elems[block]; ok {
+		if fi, err := os.Stat(c.blockPath(block)); err == nil {
+			c.size -= fi.Size()
+		}
+		c.lru.Remove(elem)
+		delete(c.elems, block)
+	}
+	c.mu.Unlock()
+	os.Remove(c.blockPath(block))
+	c.persistIndex()
+}
+
+// Clear removes all cached blocks.
+func (c *DiskBlockCache) Clear() {
+	c.mu.Lock()
+	blocks := make([]int64, 0, len(c.elems))
+	for b := range c.elems {
+		blocks = append(blocks, b)
+	}
+	c.lru = list.New()
+	c.elems = make(map[int64]*list.Element)
+	c.size = 0
+	c.mu.Unlock()
+
+	for _, b := range blocks {
+		os.Remove(c.blockPath(b))
+	}
+	c.persistIndex()
+}
+
+// Close flushes the LRU index sidecar. The cache remains usable after Close;
+// it is provided so callers can deterministically persist state before exit.
+func (c *DiskBlockCache) Close() error {
+	return c.writeIndex()
+}
+
+func (c *DiskBlockCache) persistIndex() {
+	_ = c.writeIndex()
+}
+
+func (c *DiskBlockCache) writeIndex() error {
+	c.mu.Lock()
+	entries := make([]diskCacheEntry, 0, len(c.elems))
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		block := e.Value.(int64)
+		fi, err := os.Stat(c.blockPath(block))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, diskCacheEntry{Block: block, Size: fi.Size()})
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp := c.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.indexPath())
+}
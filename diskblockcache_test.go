@@ -0,0 +1,280 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDiskBlockCache_BasicOps(t *testing.T) {
+	c, err := NewDiskBlockCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewDiskBlockCache: %v", err)
+	}
+	defer c.Close()
+
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+
+	c.Put(0, []byte("hello"))
+	data, ok := c.Get(0)
+	if !ok || !bytes.Equal(data, []byte("hello")) {
+		t.Fatalf("got %q, %v", data, ok)
+	}
+
+	c.Delete(0)
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected miss after delete")
+	}
+}
+
+func TestDiskBlockCache_EvictsOverBudget(t *testing.T) {
+	c, err := NewDiskBlockCache(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("NewDiskBlockCache: %v", err)
+	}
+	defer c.Close()
+
+	c.Put(0, bytes.Repeat([]byte("a"), 5))
+	c.Put(1, bytes.Repeat([]byte("b"), 5))
+	c.Put(2, bytes.Repeat([]byte("c"), 5)) // pushes total to 15 > 10, evicts block 0
+
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected block 0 to be evicted")
+	}
+	if _, ok := c.Get(1); !ok {
+		t.Fatal("expected block 1 to survive")
+	}
+	if _, ok := c.Get(2); !ok {
+		t.Fatal("expected block 2 to survive")
+	}
+}
+
+func TestDiskBlockCache_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := NewDiskBlockCache(dir, 0)
+	if err != nil {
+		t.Fatalf("NewDiskBlockCache: %v", err)
+	}
+	c.Put(0, []byte("persisted"))
+	c.Close()
+
+	c2, err := NewDiskBlockCache(dir, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer c2.Close()
+
+	data, ok := c2.Get(0)
+	if !ok || !bytes.Equal(data, []byte("persisted")) {
+		t.Fatalf("got %q, %v", data, ok)
+	}
+}
+
+func TestDiskBlockCache_RePutDoesNotInflateSize(t *testing.T) {
+	c, err := NewDiskBlockCache(t.TempDir(), 10)
+	if err != nil {
+		t.Fatalf("NewDiskBlockCache: %v", err)
+	}
+	defer c.Close()
+
+	// A background prefetch and a foreground fetch both storing the same
+	// block looks like this: the second Put must not double-count the
+	// block's bytes, or the cache would evict other resident blocks early.
+	c.Put(0, bytes.Repeat([]byte("a"), 5))
+	c.Put(1, bytes.Repeat([]byte("b"), 5))
+	c.Put(0, bytes.Repeat([]byte("a"), 5)) // re-put of an already-cached block
+
+	if c.size > 10 {
+		t.Fatalf("size = %d, want <= 10 after re-putting a resident block", c.size)
+	}
+	if _, ok := c.Get(1); !ok {
+		t.Fatal("expected block 1 to survive; re-put of block 0 should not have evicted it")
+	}
+}
+
+func TestLRUMemoryBlockCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUMemoryBlockCache(10)
+
+	c.Put(0, bytes.Repeat([]byte("a"), 5))
+	c.Put(1, bytes.Repeat([]byte("b"), 5))
+	c.Get(0) // touch block 0 so block 1 becomes LRU
+	c.Put(2, bytes.Repeat([]byte("c"), 5))
+
+	if _, ok := c.Get(1); ok {
+		t.Fatal("expected block 1 to be evicted as least-recently-used")
+	}
+	if _, ok := c.Get(0); !ok {
+		t.Fatal("expected block 0 to survive")
+	}
+	if c.Bytes() > 10 {
+		t.Fatalf("cache exceeds budget: %d bytes", c.Bytes())
+	}
+}
+
+func TestLRUMemoryBlockCache_Metrics(t *testing.T) {
+	c := NewLRUMemoryBlockCache(10)
+
+	c.Get(0) // miss
+	c.Put(0, bytes.Repeat([]byte("a"), 5))
+	c.Put(1, bytes.Repeat([]byte("b"), 5))
+	c.Get(0)                               // hit
+	c.Put(2, bytes.Repeat([]byte("c"), 5)) // evicts block 1
+
+	m := c.Metrics()
+	if m.Misses != 1 {
+		t.Errorf("Misses = %d, want 1", m.Misses)
+	}
+	if m.Hits != 1 {
+		t.Errorf("Hits = %d, want 1", m.Hits)
+	}
+	if m.Evictions != 1 {
+		t.Errorf("Evictions = %d, want 1", m.Evictions)
+	}
+}
+
+func TestLRUMemoryBlockCache_SetOnEventReportsEviction(t *testing.T) {
+	c := NewLRUMemoryBlockCache(10)
+
+	var events []CacheEvent
+	c.SetOnEvent(func(ev CacheEvent) { events = append(events, ev) })
+
+	c.Put(0, bytes.Repeat([]byte("a"), 5))
+	c.Put(1, bytes.Repeat([]byte("b"), 5))
+	c.Put(2, bytes.Repeat([]byte("c"), 5)) // evicts block 0
+
+	var evicted []int64
+	for _, ev := range events {
+		if ev.Kind == CacheEvict {
+			evicted = append(evicted, ev.Block)
+		}
+	}
+	if len(evicted) != 1 || evicted[0] != 0 {
+		t.Fatalf("expected a single eviction event for block 0, got %v", evicted)
+	}
+}
+
+func TestCachedBlockTransport_OnCacheEventForwardsLRUEvictions(t *testing.T) {
+	srv, _ := newBlockServer()
+	defer srv.Close()
+
+	cache := NewLRUMemoryBlockCache(512) // room for exactly one block
+	var evictions int64
+	tr := &CachedBlockTransport{
+		Transport: http.DefaultTransport,
+		Cache:     cache,
+		BlockSize: 512,
+		OnCacheEvent: func(ev CacheEvent) {
+			if ev.Kind == CacheEvict {
+				atomic.AddInt64(&evictions, 1)
+			}
+		},
+	}
+	client := &http.Client{Transport: tr}
+
+	req0, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req0.Header.Set("Range", "bytes=0-127")
+	resp0, err := client.Do(req0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp0.Body)
+	resp0.Body.Close()
+
+	req1, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req1.Header.Set("Range", "bytes=600-700")
+	resp1, err := client.Do(req1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp1.Body)
+	resp1.Body.Close()
+
+	if atomic.LoadInt64(&evictions) != 1 {
+		t.Fatalf("expected block 0 to be evicted and reported, got %d eviction events", evictions)
+	}
+}
+
+func TestLRUMemoryBlockCache_ClearRemovesEntries(t *testing.T) {
+	c := NewLRUMemoryBlockCache(0)
+	c.Put(0, []byte("x"))
+	c.Clear()
+	if c.Len() != 0 {
+		t.Fatalf("expected empty cache after Clear, got Len=%d", c.Len())
+	}
+}
+
+func TestCachedBlockTransport_WithDiskBlockCache(t *testing.T) {
+	srv, hitCount := newBlockServer()
+	defer srv.Close()
+
+	cache, err := NewDiskBlockCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("NewDiskBlockCache: %v", err)
+	}
+	defer cache.Close()
+
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     cache,
+			BlockSize: 512,
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-127")
+	for range 2 {
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	if got := atomic.LoadInt64(hitCount); got != 1 {
+		t.Fatalf("expected DiskBlockCache to serve the second request from disk, got %d origin hits", got)
+	}
+	if _, ok := cache.Get(0); !ok {
+		t.Fatal("expected block 0 to be cached on disk")
+	}
+}
+
+func TestCachedBlockTransport_WithLRUMemoryBlockCache(t *testing.T) {
+	srv, hitCount := newBlockServer()
+	defer srv.Close()
+
+	cache := NewLRUMemoryBlockCache(0)
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     cache,
+			BlockSize: 512,
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-127")
+	for range 2 {
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	if got := atomic.LoadInt64(hitCount); got != 1 {
+		t.Fatalf("expected LRUMemoryBlockCache to serve the second request from cache, got %d origin hits", got)
+	}
+	if _, ok := cache.Get(0); !ok {
+		t.Fatal("expected block 0 to be cached")
+	}
+}
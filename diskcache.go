@@ -0,0 +1,294 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskCache is a Cache that persists CachedEntry values, including their
+// validators, as files under dir, evicting the least-recently-used entries
+// once the total size on disk exceeds maxBytes. Like DiskBlockCache, it is
+// durable across process restarts: NewDiskCache rebuilds the LRU order from
+// an index.json sidecar, falling back to an mtime-ordered directory scan and
+// dropping any entry that failed to write atomically.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	lru   *list.List            // front = most recently used; values are hashed keys
+	elems map[string]*list.Element
+	size  int64
+}
+
+// diskCacheRecord is a single index.json entry.
+type diskCacheRecord struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// diskCacheFile is the on-disk envelope for one entry: its validators plus
+// the raw cached bytes.
+type diskCacheFile struct {
+	Meta Metadata `json:"meta"`
+	Data []byte   `json:"data"`
+}
+
+var _ Cache = (*DiskCache)(nil)
+
+// NewDiskCache opens (or creates) a disk-backed cache rooted at dir.
+// maxBytes <= 0 means unbounded.
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		elems:    make(map[string]*list.Element),
+	}
+	if err := c.rebuildIndex(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func hashCacheKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *DiskCache) entryPath(hash string) string {
+	return filepath.Join(c.dir, "entry-"+hash+".json")
+}
+
+func (c *DiskCache) indexPath() string {
+	return filepath.Join(c.dir, "index.json")
+}
+
+// rebuildIndex restores LRU order on open. It trusts the index.json sidecar
+// only if every entry it references still exists on disk; otherwise it
+// rescans dir and orders entries by mtime, oldest (least-recently-used)
+// first, dropping any file that fails to parse as a torn write.
+func (c *DiskCache) rebuildIndex() error {
+	if data, err := os.ReadFile(c.indexPath()); err == nil {
+		var entries []diskCacheRecord
+		if json.Unmarshal(data, &entries) == nil && c.indexMatchesDisk(entries) {
+			// writeIndex lists entries most-recently-used first.
+			for _, e := range entries {
+				elem := c.lru.PushBack(e.Hash)
+				c.elems[e.Hash] = elem
+				c.size += e.Size
+			}
+			return nil
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(c.dir, "entry-*.json"))
+	if err != nil {
+		return err
+	}
+	type scanned struct {
+		hash    string
+		size    int64
+		modTime time.Time
+	}
+	found := make([]scanned, 0, len(matches))
+	for _, p := range matches {
+		fi, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var f diskCacheFile
+		if json.Unmarshal(data, &f) != nil {
+			os.Remove(p) // torn write; drop it
+			continue
+		}
+		hash := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(p), "entry-"), ".json")
+		found = append(found, scanned{hash, fi.Size(), fi.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.Before(found[j].modTime) })
+	for _, f := range found {
+		elem := c.lru.PushFront(f.hash)
+		c.elems[f.hash] = elem
+		c.size += f.size
+	}
+	return nil
+}
+
+func (c *DiskCache) indexMatchesDisk(entries []diskCacheRecord) bool {
+	for _, e := range entries {
+		if _, err := os.Stat(c.entryPath(e.Hash)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the cached entry for key, if present.
+func (c *DiskCache) Get(key string) (*CachedEntry, bool) {
+	hash := hashCacheKey(key)
+	c.mu.Lock()
+	elem, ok := c.elems[hash]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.entryPath(hash))
+	if err != nil {
+		return nil, false
+	}
+	var f diskCacheFile
+	if json.Unmarshal(data, &f) != nil {
+		return nil, false
+	}
+	return &CachedEntry{Data: f.Data, Meta: f.Meta}, true
+}
+
+// Put writes entry to disk under key and evicts least-recently-used entries
+// until the cache fits within maxBytes.
+func (c *DiskCache) Put(key string, entry *CachedEntry) {
+	hash := hashCacheKey(key)
+	data, err := json.Marshal(diskCacheFile{Meta: entry.Meta, Data: entry.Data})
+	if err != nil {
+		return
+	}
+
+	path := c.entryPath(hash)
+	var oldSize int64
+	if fi, err := os.Stat(path); err == nil {
+		oldSize = fi.Size()
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.elems[hash]; ok {
+		c.lru.MoveToFront(elem)
+		c.size -= oldSize
+	} else {
+		elem := c.lru.PushFront(hash)
+		c.elems[hash] = elem
+	}
+	c.size += int64(len(data))
+	c.evictLocked()
+	c.mu.Unlock()
+
+	c.persistIndex()
+}
+
+// evictLocked removes least-recently-used entries until the cache is within
+// budget. c.mu must be held.
+func (c *DiskCache) evictLocked() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	for c.size > c.maxBytes {
+		back := c.lru.Back()
+		if back == nil {
+			return
+		}
+		hash := back.Value.(string)
+		if fi, err := os.Stat(c.entryPath(hash)); err == nil {
+			c.size -= fi.Size()
+		}
+		os.Remove(c.entryPath(hash))
+		c.lru.Remove(back)
+		delete(c.elems, hash)
+	}
+}
+
+// Delete removes a single entry.
+func (c *DiskCache) Delete(key string) {
+	hash := hashCacheKey(key)
+	c.mu.Lock()
+	if elem, ok := c.elems[hash]; ok {
+		if fi, err := os.Stat(c.entryPath(hash)); err == nil {
+			c.size -= fi.Size()
+		}
+		c.lru.Remove(elem)
+		delete(c.elems, hash)
+	}
+	c.mu.Unlock()
+	os.Remove(c.entryPath(hash))
+	c.persistIndex()
+}
+
+// Clear removes all cached entries.
+func (c *DiskCache) Clear() {
+	c.mu.Lock()
+	hashes := make([]string, 0, len(c.elems))
+	for h := range c.elems {
+		hashes = append(hashes, h)
+	}
+	c.lru = list.New()
+	c.elems = make(map[string]*list.Element)
+	c.size = 0
+	c.mu.Unlock()
+
+	for _, h := range hashes {
+		os.Remove(c.entryPath(h))
+	}
+	c.persistIndex()
+}
+
+// Close flushes the LRU index sidecar. The cache remains usable after
+// Close; it is provided so callers can deterministically persist state
+// before exit.
+func (c *DiskCache) Close() error {
+	return c.writeIndex()
+}
+
+func (c *DiskCache) persistIndex() {
+	_ = c.writeIndex()
+}
+
+func (c *DiskCache) writeIndex() error {
+	c.mu.Lock()
+	entries := make([]diskCacheRecord, 0, len(c.elems))
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		hash := e.Value.(string)
+		fi, err := os.Stat(c.entryPath(hash))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, diskCacheRecord{Hash: hash, Size: fi.Size()})
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp := c.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.indexPath())
+}
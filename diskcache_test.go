@@ -0,0 +1,97 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"testing"
+)
+
+func TestDiskCache_BasicOps(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+
+	c.Put("a", &CachedEntry{Data: []byte("hello"), Meta: Metadata{ETag: `"v1"`, Length: 5}})
+	entry, ok := c.Get("a")
+	if !ok {
+		t.Fatal("expected hit after Put")
+	}
+	if string(entry.Data) != "hello" || entry.Meta.ETag != `"v1"` {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected miss after Delete")
+	}
+}
+
+func TestDiskCache_EvictsOverBudget(t *testing.T) {
+	dir := t.TempDir()
+	// Each entry's on-disk JSON envelope (validators + base64 data) runs
+	// ~85 bytes for 16 bytes of payload; budget for roughly two entries.
+	c, err := NewDiskCache(dir, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		key := string(rune('a' + i))
+		c.Put(key, &CachedEntry{Data: make([]byte, 16)})
+	}
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected earliest entry to be evicted")
+	}
+	if _, ok := c.Get("j"); !ok {
+		t.Fatal("expected most recent entry to survive")
+	}
+}
+
+func TestDiskCache_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", &CachedEntry{Data: []byte("persisted"), Meta: Metadata{ETag: `"v7"`}})
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, ok := reopened.Get("a")
+	if !ok {
+		t.Fatal("expected entry to survive reopen")
+	}
+	if string(entry.Data) != "persisted" || entry.Meta.ETag != `"v7"` {
+		t.Fatalf("unexpected entry after reopen: %+v", entry)
+	}
+}
+
+func TestDiskCache_Clear(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", &CachedEntry{Data: []byte("x")})
+	c.Put("b", &CachedEntry{Data: []byte("y")})
+	c.Clear()
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected Clear to remove entry a")
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected Clear to remove entry b")
+	}
+}
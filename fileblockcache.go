@@ -0,0 +1,212 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// fileBlockCacheBitmapSuffix names the sidecar NewFileBlockCache creates
+// next to its backing file to persist the validity Bitset across restarts.
+const fileBlockCacheBitmapSuffix = ".bitmap"
+
+// FileBlockCache is a BlockCache backed by a single sparse file,
+// written to with WriteAt/ReadAt (pwrite/pread) rather than mmap'd like
+// MmapFileBlockCache/MmapBlockCache. Block presence is tracked by a
+// Bitset, persisted to a path+".bitmap" sidecar so the cache survives a
+// restart. Because unwritten regions of the file are never explicitly
+// zeroed, only the blocks actually Put consume disk space -- useful for
+// caching large media or OCI-layer downloads without holding gigabytes in
+// RAM or materializing the whole thing on disk up front.
+type FileBlockCache struct {
+	f          *os.File
+	bitmapFile *os.File // sidecar persisting valid; nil if it couldn't be opened
+	totalSize  int64
+	blockSize  int64
+	numBlocks  int64
+	valid      *Bitset
+	mu         sync.RWMutex
+}
+
+var _ BlockCache = (*FileBlockCache)(nil)
+
+// NewFileBlockCache opens (or creates) a sparse-file-backed block
+// cache at path, truncated to totalSize bytes at blockSize granularity.
+// Truncating doesn't allocate disk blocks, so the file starts out empty on
+// disk regardless of totalSize; Put fills in only the blocks it's given.
+// Block presence is tracked in a Bitset sized to ceil(totalSize/blockSize)
+// and mirrored to a path+".bitmap" sidecar, loaded back in if it already
+// matches this layout.
+func NewFileBlockCache(path string, totalSize, blockSize int64) (*FileBlockCache, error) {
+	if blockSize <= 0 || totalSize <= 0 {
+		return nil, fmt.Errorf("invalid sizes: total=%d block=%d", totalSize, blockSize)
+	}
+	numBlocks := (totalSize + blockSize - 1) / blockSize
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(totalSize); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	valid := NewBitset(int(numBlocks))
+	bitmapFile := loadOrCreateBitmap(path+fileBlockCacheBitmapSuffix, valid)
+
+	return &FileBlockCache{
+		f:          f,
+		bitmapFile: bitmapFile,
+		totalSize:  totalSize,
+		blockSize:  blockSize,
+		numBlocks:  numBlocks,
+		valid:      valid,
+	}, nil
+}
+
+// loadOrCreateBitmap opens path as valid's persisted sidecar, loading its
+// contents into valid if its size already matches and resizing it
+// otherwise. Persistence is optional: if path can't be opened, it returns
+// nil and valid is simply kept in memory for this process's lifetime.
+func loadOrCreateBitmap(path string, valid *Bitset) *os.File {
+	bf, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil
+	}
+	bitmapSize := int64(len(valid.bits)) * 8
+	if fi, err := bf.Stat(); err == nil && fi.Size() == bitmapSize {
+		raw := make([]byte, bitmapSize)
+		if _, err := io.ReadFull(bf, raw); err == nil {
+			for i := range valid.bits {
+				valid.bits[i] = binary.BigEndian.Uint64(raw[i*8 : i*8+8])
+			}
+			return bf
+		}
+	}
+	bf.Truncate(bitmapSize)
+	return bf
+}
+
+// persistBit mirrors the 64-bit word covering block into the bitmap
+// sidecar, if one is open. c.mu must be held.
+func (c *FileBlockCache) persistBit(block int64) {
+	if c.bitmapFile == nil {
+		return
+	}
+	word := block / 64
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], c.valid.bits[word])
+	c.bitmapFile.WriteAt(buf[:], word*8)
+}
+
+// Get returns the block data if present; otherwise false. A short final
+// block (when totalSize isn't a multiple of blockSize) returns only the
+// bytes actually on disk.
+func (c *FileBlockCache) Get(block int64) ([]byte, bool) {
+	if block < 0 || block >= c.numBlocks {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.valid.Get(int(block)) {
+		return nil, false
+	}
+	data := make([]byte, c.blockSize)
+	n, err := c.f.ReadAt(data, block*c.blockSize)
+	if err != nil && err != io.EOF {
+		return nil, false
+	}
+	return data[:n], true
+}
+
+// Put writes data for block at block*blockSize via WriteAt and marks the
+// block present. Untouched bytes elsewhere in the file stay a sparse hole.
+func (c *FileBlockCache) Put(block int64, data []byte) {
+	if block < 0 || block >= c.numBlocks {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.f.WriteAt(data, block*c.blockSize); err != nil {
+		return
+	}
+	c.valid.Set(int(block))
+	c.persistBit(block)
+}
+
+// Delete marks block absent without touching its bytes on disk, leaving
+// the sparse hole in place rather than materializing it by writing zeros.
+func (c *FileBlockCache) Delete(block int64) {
+	if block < 0 || block >= c.numBlocks {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.valid.Clear(int(block))
+	c.persistBit(block)
+}
+
+// Clear truncates the backing file (and the bitmap sidecar, if any) down
+// to empty and back up to their original sizes, releasing every block
+// written so far without writing a single zero byte.
+func (c *FileBlockCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.f.Truncate(0)
+	c.f.Truncate(c.totalSize)
+	for i := range c.valid.bits {
+		c.valid.bits[i] = 0
+	}
+	if c.bitmapFile != nil {
+		bitmapSize := int64(len(c.valid.bits)) * 8
+		c.bitmapFile.Truncate(0)
+		c.bitmapFile.Truncate(bitmapSize)
+	}
+}
+
+// Sync fsyncs the backing file and the bitmap sidecar, if one is open.
+func (c *FileBlockCache) Sync() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if err := c.f.Sync(); err != nil {
+		return err
+	}
+	if c.bitmapFile != nil {
+		return c.bitmapFile.Sync()
+	}
+	return nil
+}
+
+// Close syncs and closes the backing file and the bitmap sidecar.
+func (c *FileBlockCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	syncErr := c.f.Sync()
+	closeErr := c.f.Close()
+	var bitmapErr error
+	if c.bitmapFile != nil {
+		c.bitmapFile.Sync()
+		bitmapErr = c.bitmapFile.Close()
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return bitmapErr
+}
+
+// Size returns the backing file's total size.
+func (c *FileBlockCache) Size() int64 { return c.totalSize }
+
+// NumBlocks returns the number of blocks.
+func (c *FileBlockCache) NumBlocks() int64 { return c.numBlocks }
+
+// BlockSize returns the block size.
+func (c *FileBlockCache) BlockSize() int64 { return c.blockSize }
@@ -0,0 +1,131 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileBlockCache_BasicOps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewFileBlockCache(path, 4096, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected miss before Put")
+	}
+
+	data := bytes.Repeat([]byte{0x42}, 512)
+	c.Put(0, data)
+
+	got, ok := c.Get(0)
+	if !ok {
+		t.Fatal("expected hit after Put")
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %x want %x", got, data)
+	}
+
+	c.Delete(0)
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected miss after Delete")
+	}
+}
+
+func TestFileBlockCache_TruncatesToLogicalSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	const totalSize = 1 << 30 // 1 GiB, of which only one 4096-byte block is ever written
+	c, err := NewFileBlockCache(path, totalSize, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Put(0, bytes.Repeat([]byte{0x1}, 4096))
+
+	// Truncate sets the file's logical size without writing (or allocating
+	// disk space for) the untouched regions; the backing file should reach
+	// this point almost instantly despite being "1 GiB", which wouldn't be
+	// true if Put or the constructor materialized it.
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != totalSize {
+		t.Fatalf("logical size = %d, want %d", fi.Size(), int64(totalSize))
+	}
+}
+
+func TestFileBlockCache_SurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewFileBlockCache(path, 4096, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte{0x7a}, 512)
+	c.Put(3, data)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileBlockCache(path, 4096, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	got, ok := reopened.Get(3)
+	if !ok {
+		t.Fatal("expected block to survive reopen via the bitmap sidecar")
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %x want %x", got, data)
+	}
+	if _, ok := reopened.Get(0); ok {
+		t.Fatal("expected block 0 to still be absent after reopen")
+	}
+}
+
+func TestFileBlockCache_Clear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewFileBlockCache(path, 4096, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Put(0, bytes.Repeat([]byte{0x1}, 512))
+	c.Clear()
+
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected miss after Clear")
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 4096 {
+		t.Fatalf("expected backing file to keep its logical size after Clear, got %+v, err=%v", fi, err)
+	}
+}
+
+func TestFileBlockCache_Sync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewFileBlockCache(path, 4096, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Put(0, bytes.Repeat([]byte{0x99}, 512))
+	if err := c.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	got, ok := c.Get(0)
+	if !ok || !bytes.Equal(got, bytes.Repeat([]byte{0x99}, 512)) {
+		t.Fatalf("expected block 0 to remain readable after Sync, got %x (ok=%v)", got, ok)
+	}
+}
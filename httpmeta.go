@@ -59,6 +59,35 @@ func (m Metadata) Equal(other Metadata) bool {
 	return true
 }
 
+// parseContentRange parses a "bytes start-end/total" Content-Range value,
+// as found on the parts of a multipart/byteranges response.
+func parseContentRange(cr string) (start, end int64, ok bool) {
+	cr = strings.TrimPrefix(cr, "bytes ")
+	slash := strings.IndexByte(cr, '/')
+	if slash < 0 {
+		return 0, 0, false
+	}
+	dash := strings.IndexByte(cr[:slash], '-')
+	if dash < 0 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(cr[:dash], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err = strconv.ParseInt(cr[dash+1:slash], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// FromHeaders is an alias for extractMetadata, kept for call sites in the
+// range-level cache that predate extractMetadata's name.
+func FromHeaders(h http.Header) Metadata {
+	return extractMetadata(h)
+}
+
 // ApplyValidators adds conditional headers to a request (for conditional GETs).
 func (m Metadata) ApplyValidators(h http.Header) {
 	if m.ETag != "" {
@@ -5,6 +5,9 @@ package httpseek
 import (
 	"bytes"
 	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -77,3 +80,62 @@ func TestNoopLogger(t *testing.T) {
 func TestLogFuncImplementsLogger(t *testing.T) {
 	var _ Logger = LogFunc(func(level, msg string, args ...any) {})
 }
+
+func TestSetSlogLoggerEmitsStructuredAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	SetSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer SetLogger(nil)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-3/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("abcd"))
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-3")
+	start := logRequest(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	logResponse(resp, start)
+
+	out := buf.String()
+	for _, want := range []string{"method=GET", `range="bytes=0-3"`, "status=206", "bytes=4", "duration_ms="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected log output to contain %q, got: %q", want, out)
+		}
+	}
+}
+
+func TestDumpWireRedactsSensitiveHeaders(t *testing.T) {
+	var dumped string
+	SetLogger(LogFunc(func(level, msg string, args ...any) {
+		if msg == "" {
+			dumped += args[0].(string)
+		}
+	}))
+	defer SetLogger(nil)
+	SetDumpWire(true)
+	defer SetDumpWire(false)
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.invalid/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-token")
+	logRequest(req)
+
+	if strings.Contains(dumped, "secret-token") {
+		t.Errorf("expected Authorization header to be redacted, got dump: %q", dumped)
+	}
+	if !strings.Contains(dumped, "REDACTED") {
+		t.Errorf("expected dump to contain REDACTED, got: %q", dumped)
+	}
+	if req.Header.Get("Authorization") != "Bearer secret-token" {
+		t.Errorf("dumping must not mutate the real request header, got: %q", req.Header.Get("Authorization"))
+	}
+}
@@ -5,8 +5,10 @@ package httpseek
 import (
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
+	"time"
 )
 
 // Logger is a minimal interface for debug/error logging.
@@ -39,22 +41,134 @@ func SetLogger(l Logger) {
 	logger = l
 }
 
-func logRequest(req *http.Request) {
-	if logger != nil {
-		if dump, err := httputil.DumpRequestOut(req, true); err == nil {
-			logger.Debug("", string(dump))
-		} else {
-			logger.Error("Failed to dump request", err)
-		}
+// slogAdapter adapts a *slog.Logger to the Logger interface, so Debug/Error
+// calls made by logRequest/logResponse become structured slog records.
+type slogAdapter struct{ l *slog.Logger }
+
+func (a slogAdapter) Debug(msg string, args ...any) { a.l.Debug(msg, args...) }
+func (a slogAdapter) Error(msg string, args ...any) { a.l.Error(msg, args...) }
+
+// SetSlogLogger installs l as the package logger. It's a thin convenience
+// over SetLogger(slogAdapter{l}); Debug calls become slog.LevelDebug
+// records and Error calls become slog.LevelError records.
+func SetSlogLogger(l *slog.Logger) {
+	SetLogger(slogAdapter{l})
+}
+
+// defaultRedactHeaders lists the header names whose values are replaced
+// with REDACTED in wire dumps (see SetDumpWire) because they commonly carry
+// credentials.
+var defaultRedactHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie"}
+
+var redactHeaders = defaultRedactHeaders
+
+// SetRedactHeaders overrides the header names redacted in request/response
+// dumps enabled by SetDumpWire. The default redacts Authorization, Cookie,
+// Proxy-Authorization, and Set-Cookie.
+func SetRedactHeaders(headers []string) {
+	redactHeaders = headers
+}
+
+// dumpWire controls whether logRequest/logResponse additionally emit the
+// full HTTP wire form, for the rare case the structured summary isn't
+// enough to debug a problem.
+var dumpWire bool
+
+// SetDumpWire enables or disables full request/response wire dumps at
+// Debug level. Values of headers named via SetRedactHeaders are replaced
+// with REDACTED in the dump first so it can't leak credentials into logs.
+func SetDumpWire(enabled bool) {
+	dumpWire = enabled
+}
+
+// logRequest logs a structured summary of req at Debug level and returns
+// the time it was called, so the matching logResponse can report how long
+// the round trip took. It's a no-op (aside from the timestamp) if no
+// logger is installed.
+func logRequest(req *http.Request) time.Time {
+	start := time.Now()
+	if logger == nil {
+		return start
+	}
+	logger.Debug("request",
+		"method", req.Method,
+		"url", req.URL.String(),
+		"range", req.Header.Get("Range"),
+	)
+	if dumpWire {
+		dumpRequest(req)
+	}
+	return start
+}
+
+// logResponse logs a structured summary of resp at Debug level, including
+// the elapsed time since the matching logRequest call.
+func logResponse(resp *http.Response, start time.Time) {
+	if logger == nil {
+		return
+	}
+	logger.Debug("response",
+		"status", resp.StatusCode,
+		"range", resp.Header.Get("Content-Range"),
+		"content_length", resp.ContentLength,
+		"bytes", rangeByteCount(resp),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	if dumpWire {
+		dumpResponse(resp)
+	}
+}
+
+// rangeByteCount returns the number of bytes actually covered by resp's
+// Content-Range (e.g. "bytes 0-511/1024" -> 512), which is the size of the
+// range served regardless of how the body is framed on the wire. It falls
+// back to resp.ContentLength when Content-Range is absent or unparseable:
+// -1 when the length is unknown (e.g. a chunked body), and for a
+// multipart/byteranges body the whole body, part headers and boundaries
+// included.
+func rangeByteCount(resp *http.Response) int64 {
+	var start, end int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d", &start, &end); err == nil {
+		return end - start + 1
+	}
+	return resp.ContentLength
+}
+
+// dumpRequest logs the full wire form of req, redacting sensitive headers
+// first. It dumps a clone so redaction never touches the headers actually
+// sent over the wire.
+func dumpRequest(req *http.Request) {
+	clone := req.Clone(req.Context())
+	clone.Header = req.Header.Clone()
+	redact(clone.Header)
+	if dump, err := httputil.DumpRequestOut(clone, true); err == nil {
+		logger.Debug("", string(dump))
+	} else {
+		logger.Error("Failed to dump request", err)
+	}
+}
+
+// dumpResponse logs the full wire form of resp, redacting sensitive
+// headers first.
+func dumpResponse(resp *http.Response) {
+	orig := resp.Header
+	resp.Header = orig.Clone()
+	redact(resp.Header)
+	dump, err := httputil.DumpResponse(resp, true)
+	resp.Header = orig
+	if err == nil {
+		logger.Debug("", string(dump))
+	} else {
+		logger.Error("Failed to dump response", err)
 	}
 }
 
-func logResponse(resp *http.Response) {
-	if logger != nil {
-		if dump, err := httputil.DumpResponse(resp, true); err == nil {
-			logger.Debug("", string(dump))
-		} else {
-			logger.Error("Failed to dump response", err)
+// redact overwrites the values of any header in redactHeaders that's
+// present in h.
+func redact(h http.Header) {
+	for _, name := range redactHeaders {
+		if h.Get(name) != "" {
+			h.Set(name, "REDACTED")
 		}
 	}
 }
@@ -0,0 +1,175 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheEventKind identifies what kind of cache event a CacheEvent reports.
+type CacheEventKind int
+
+const (
+	CacheHit CacheEventKind = iota
+	CacheMiss
+	CacheEvict
+)
+
+// CacheEvent describes a single cache access or eviction, for callers that
+// want to observe cache behavior (e.g. via CachedBlockTransport.OnCacheEvent)
+// without depending on a specific BlockCache implementation's metrics API.
+type CacheEvent struct {
+	Kind  CacheEventKind
+	Block int64
+}
+
+// CacheMetrics tallies hits, misses, and evictions for a BlockCache
+// implementation that tracks them (currently just LRUMemoryBlockCache).
+type CacheMetrics struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+// LRUMemoryBlockCache is an in-memory BlockCache bounded by total byte size.
+// Once Put would push the cache over maxBytes, the least-recently-used
+// blocks are evicted until it fits again.
+type LRUMemoryBlockCache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	lru     *list.List
+	elems   map[int64]*list.Element
+	size    int64
+	metrics CacheMetrics
+	onEvent func(CacheEvent)
+}
+
+type lruBlockEntry struct {
+	block int64
+	data  []byte
+}
+
+var _ BlockCache = (*LRUMemoryBlockCache)(nil)
+
+// NewLRUMemoryBlockCache returns a BlockCache that never holds more than
+// maxBytes of block data. maxBytes <= 0 means unbounded.
+func NewLRUMemoryBlockCache(maxBytes int64) *LRUMemoryBlockCache {
+	return &LRUMemoryBlockCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		elems:    make(map[int64]*list.Element),
+	}
+}
+
+// SetOnEvent installs fn to be called for every hit, miss, and eviction.
+// fn is called with c.mu held, so it must not call back into c.
+func (c *LRUMemoryBlockCache) SetOnEvent(fn func(CacheEvent)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvent = fn
+}
+
+// Metrics returns a snapshot of the cache's hit/miss/eviction counters.
+func (c *LRUMemoryBlockCache) Metrics() CacheMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metrics
+}
+
+// Get returns the block data if present, promoting it to most-recently-used.
+func (c *LRUMemoryBlockCache) Get(block int64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.elems[block]
+	if !ok {
+		c.metrics.Misses++
+		c.emit(CacheEvent{Kind: CacheMiss, Block: block})
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	c.metrics.Hits++
+	c.emit(CacheEvent{Kind: CacheHit, Block: block})
+	return elem.Value.(*lruBlockEntry).data, true
+}
+
+// emit calls onEvent if set. Callers must hold c.mu.
+func (c *LRUMemoryBlockCache) emit(ev CacheEvent) {
+	if c.onEvent != nil {
+		c.onEvent(ev)
+	}
+}
+
+// Put stores data for block and evicts least-recently-used blocks until the
+// cache fits within maxBytes.
+func (c *LRUMemoryBlockCache) Put(block int64, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.elems[block]; ok {
+		c.size -= int64(len(elem.Value.(*lruBlockEntry).data))
+		elem.Value.(*lruBlockEntry).data = data
+		c.size += int64(len(data))
+		c.lru.MoveToFront(elem)
+	} else {
+		elem := c.lru.PushFront(&lruBlockEntry{block: block, data: data})
+		c.elems[block] = elem
+		c.size += int64(len(data))
+	}
+
+	if c.maxBytes <= 0 {
+		return
+	}
+	for c.size > c.maxBytes {
+		back := c.lru.Back()
+		if back == nil {
+			return
+		}
+		entry := back.Value.(*lruBlockEntry)
+		c.size -= int64(len(entry.data))
+		c.lru.Remove(back)
+		delete(c.elems, entry.block)
+		c.metrics.Evictions++
+		c.emit(CacheEvent{Kind: CacheEvict, Block: entry.block})
+	}
+}
+
+// Delete removes a single block.
+func (c *LRUMemoryBlockCache) Delete(block int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.elems[block]
+	if !ok {
+		return
+	}
+	c.size -= int64(len(elem.Value.(*lruBlockEntry).data))
+	c.lru.Remove(elem)
+	delete(c.elems, block)
+}
+
+// Clear removes all cached blocks.
+func (c *LRUMemoryBlockCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru = list.New()
+	c.elems = make(map[int64]*list.Element)
+	c.size = 0
+}
+
+// Close is a no-op; LRUMemoryBlockCache holds no resources beyond the Go heap.
+func (c *LRUMemoryBlockCache) Close() error { return nil }
+
+// Len returns the number of cached blocks.
+func (c *LRUMemoryBlockCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.elems)
+}
+
+// Bytes returns the total size in bytes of all cached blocks.
+func (c *LRUMemoryBlockCache) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
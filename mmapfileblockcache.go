@@ -0,0 +1,246 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+//go:build unix
+
+package httpseek
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	mmapFileBlockCacheMagic   uint32 = 0x48535042 // "HSPB"
+	mmapFileBlockCacheVersion uint32 = 1
+
+	// mmapFileBlockCacheHeaderSize is magic(4) + version(4) + blockSize(8) +
+	// numBlocks(8) + fingerprint(32).
+	mmapFileBlockCacheHeaderSize int64 = 4 + 4 + 8 + 8 + 32
+)
+
+// ErrMmapFileBlockCacheMismatch is returned by NewMmapFileBlockCache when an
+// existing backing file's header doesn't match the requested layout or
+// fingerprint.
+var ErrMmapFileBlockCacheMismatch = errors.New("httpseek: mmap file block cache header mismatch")
+
+// MmapFileBlockCache is a BlockCache backed by a single memory-mapped file,
+// so a long-running process can resume its block cache across restarts
+// instead of losing it like the anonymous-mapping MmapBlockCache. The file
+// begins with a header (magic, version, block layout, and a caller-supplied
+// fingerprint identifying the resource version) followed by a persisted
+// validity bitmap and the block data itself. NewMmapFileBlockCache rejects
+// an existing file whose header doesn't match rather than risk serving
+// bytes left over from a different resource or layout.
+type MmapFileBlockCache struct {
+	f         *os.File
+	data      []byte // full mmap: header + bitmap + blocks
+	blockSize int64
+	numBlocks int64
+	bitmap    []byte // subslice of data
+	blocks    []byte // subslice of data
+	mu        sync.RWMutex
+}
+
+var _ BlockCache = (*MmapFileBlockCache)(nil)
+
+// NewMmapFileBlockCache opens (or creates) a memory-mapped block cache
+// backed by the file at path, sized for totalSize bytes at blockSize
+// granularity.
+// fingerprint should identify the specific resource version being cached
+// (e.g. derived from its URL and ETag); reopening the same path with a
+// different fingerprint, block size, or total size returns
+// ErrMmapFileBlockCacheMismatch instead of silently reusing stale blocks.
+func NewMmapFileBlockCache(path string, totalSize, blockSize int64, fingerprint string) (*MmapFileBlockCache, error) {
+	if blockSize <= 0 || totalSize <= 0 {
+		return nil, fmt.Errorf("invalid sizes: total=%d block=%d", totalSize, blockSize)
+	}
+	if totalSize%blockSize != 0 {
+		return nil, fmt.Errorf("total size must be a multiple of block size")
+	}
+	numBlocks := totalSize / blockSize
+	bitmapSize := (numBlocks + 7) / 8
+	fileSize := mmapFileBlockCacheHeaderSize + bitmapSize + totalSize
+	fp := sha256.Sum256([]byte(fingerprint))
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	fresh := fi.Size() == 0
+	switch {
+	case fresh:
+		if err := f.Truncate(fileSize); err != nil {
+			f.Close()
+			return nil, err
+		}
+	case fi.Size() != fileSize:
+		f.Close()
+		return nil, ErrMmapFileBlockCacheMismatch
+	}
+
+	data, err := unix.Mmap(int(f.Fd()), 0, int(fileSize), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		return nil, os.NewSyscallError("mmap", err)
+	}
+
+	c := &MmapFileBlockCache{
+		f:         f,
+		data:      data,
+		blockSize: blockSize,
+		numBlocks: numBlocks,
+		bitmap:    data[mmapFileBlockCacheHeaderSize : mmapFileBlockCacheHeaderSize+bitmapSize],
+		blocks:    data[mmapFileBlockCacheHeaderSize+bitmapSize:],
+	}
+
+	if fresh {
+		binary.BigEndian.PutUint32(data[0:4], mmapFileBlockCacheMagic)
+		binary.BigEndian.PutUint32(data[4:8], mmapFileBlockCacheVersion)
+		binary.BigEndian.PutUint64(data[8:16], uint64(blockSize))
+		binary.BigEndian.PutUint64(data[16:24], uint64(numBlocks))
+		copy(data[24:56], fp[:])
+		return c, nil
+	}
+
+	if binary.BigEndian.Uint32(data[0:4]) != mmapFileBlockCacheMagic ||
+		binary.BigEndian.Uint32(data[4:8]) != mmapFileBlockCacheVersion ||
+		binary.BigEndian.Uint64(data[8:16]) != uint64(blockSize) ||
+		binary.BigEndian.Uint64(data[16:24]) != uint64(numBlocks) ||
+		string(data[24:56]) != string(fp[:]) {
+		unix.Munmap(data)
+		f.Close()
+		return nil, ErrMmapFileBlockCacheMismatch
+	}
+
+	return c, nil
+}
+
+func bitGet(bitmap []byte, i int64) bool {
+	return bitmap[i/8]&(1<<(uint(i)%8)) != 0
+}
+
+func bitSet(bitmap []byte, i int64) {
+	bitmap[i/8] |= 1 << (uint(i) % 8)
+}
+
+func bitClear(bitmap []byte, i int64) {
+	bitmap[i/8] &^= 1 << (uint(i) % 8)
+}
+
+// Clear invalidates all cached blocks but keeps the mapping.
+func (c *MmapFileBlockCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.bitmap {
+		c.bitmap[i] = 0
+	}
+	for i := range c.blocks {
+		c.blocks[i] = 0
+	}
+}
+
+// Delete invalidates a specific block.
+func (c *MmapFileBlockCache) Delete(block int64) {
+	if block < 0 || block >= c.numBlocks {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bitClear(c.bitmap, block)
+	start := block * c.blockSize
+	for i := start; i < start+c.blockSize; i++ {
+		c.blocks[i] = 0
+	}
+}
+
+// Get returns the block data if valid; otherwise false.
+func (c *MmapFileBlockCache) Get(block int64) ([]byte, bool) {
+	if block < 0 || block >= c.numBlocks {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !bitGet(c.bitmap, block) {
+		return nil, false
+	}
+	start := block * c.blockSize
+	end := start + c.blockSize
+	return c.blocks[start:end:end], true
+}
+
+// Put stores data for a block and marks it as valid.
+// If len(data) < blockSize, the remainder is zero-filled.
+func (c *MmapFileBlockCache) Put(block int64, data []byte) {
+	if block < 0 || block >= c.numBlocks {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	start := block * c.blockSize
+	end := start + c.blockSize
+	copy(c.blocks[start:end], data)
+	if int64(len(data)) < c.blockSize {
+		for i := start + int64(len(data)); i < end; i++ {
+			c.blocks[i] = 0
+		}
+	}
+	bitSet(c.bitmap, block)
+}
+
+// Sync fsyncs the cache's current contents to disk without closing it, so a
+// long-running caller can checkpoint progress (e.g. before exiting a loop
+// early) without giving up the mapping.
+func (c *MmapFileBlockCache) Sync() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.data == nil {
+		return nil
+	}
+	if err := unix.Msync(c.data, unix.MS_SYNC); err != nil {
+		return os.NewSyscallError("msync", err)
+	}
+	return nil
+}
+
+// Close flushes the mapping to disk and unmaps it.
+func (c *MmapFileBlockCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data == nil {
+		return nil
+	}
+	syncErr := unix.Msync(c.data, unix.MS_SYNC)
+	err := unix.Munmap(c.data)
+	c.data = nil
+	closeErr := c.f.Close()
+	if syncErr != nil {
+		return os.NewSyscallError("msync", syncErr)
+	}
+	if err != nil {
+		return os.NewSyscallError("munmap", err)
+	}
+	return closeErr
+}
+
+// Size returns total block-data size (excluding header and bitmap).
+func (c *MmapFileBlockCache) Size() int64 { return int64(len(c.blocks)) }
+
+// NumBlocks returns number of blocks.
+func (c *MmapFileBlockCache) NumBlocks() int64 { return c.numBlocks }
+
+// BlockSize returns block size.
+func (c *MmapFileBlockCache) BlockSize() int64 { return c.blockSize }
@@ -0,0 +1,113 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+//go:build unix
+
+package httpseek
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestMmapFileBlockCache_BasicOps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewMmapFileBlockCache(path, 4096, 512, "fp-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected miss before Put")
+	}
+
+	data := bytes.Repeat([]byte{0x42}, 512)
+	c.Put(0, data)
+
+	got, ok := c.Get(0)
+	if !ok {
+		t.Fatal("expected hit after Put")
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %x want %x", got, data)
+	}
+
+	c.Delete(0)
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected miss after Delete")
+	}
+}
+
+func TestMmapFileBlockCache_SurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewMmapFileBlockCache(path, 4096, 512, "fp-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte{0x7a}, 512)
+	c.Put(3, data)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewMmapFileBlockCache(path, 4096, 512, "fp-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	got, ok := reopened.Get(3)
+	if !ok {
+		t.Fatal("expected block to survive reopen")
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %x want %x", got, data)
+	}
+}
+
+func TestMmapFileBlockCache_Sync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewMmapFileBlockCache(path, 4096, 512, "fp-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Put(0, bytes.Repeat([]byte{0x99}, 512))
+	if err := c.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	// Sync must not invalidate the mapping; the cache stays usable.
+	got, ok := c.Get(0)
+	if !ok || !bytes.Equal(got, bytes.Repeat([]byte{0x99}, 512)) {
+		t.Fatalf("expected block 0 to remain readable after Sync, got %x (ok=%v)", got, ok)
+	}
+}
+
+func TestMmapFileBlockCache_RejectsFingerprintMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewMmapFileBlockCache(path, 4096, 512, "fp-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	if _, err := NewMmapFileBlockCache(path, 4096, 512, "fp-2"); err != ErrMmapFileBlockCacheMismatch {
+		t.Fatalf("expected ErrMmapFileBlockCacheMismatch, got %v", err)
+	}
+}
+
+func TestMmapFileBlockCache_RejectsSizeMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.bin")
+	c, err := NewMmapFileBlockCache(path, 4096, 512, "fp-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	if _, err := NewMmapFileBlockCache(path, 8192, 512, "fp-1"); err != ErrMmapFileBlockCacheMismatch {
+		t.Fatalf("expected ErrMmapFileBlockCacheMismatch, got %v", err)
+	}
+}
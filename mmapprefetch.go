@@ -0,0 +1,241 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultMinPrefetchWindow and defaultMaxPrefetchWindow bound
+// PrefetchingReaderAt's window when MinWindow/MaxWindow are unset.
+const (
+	defaultMinPrefetchWindow = 1
+	defaultMaxPrefetchWindow = 32
+)
+
+// PrefetchingReaderAt wraps a ReaderAtHTTP with an MmapBlockCache and
+// speculatively prefetches ahead of sequential access patterns, so
+// consumers like video or tar readers stop paying one round-trip per block
+// miss. On each ReadAt, a request that continues the previous one's
+// trailing block doubles the prefetch window (up to MaxWindow); any other
+// access collapses it back to MinWindow. Prefetch fetches run in
+// background goroutines bounded by MaxInFlightBytes, and share in-flight
+// requests with overlapping real reads via singleflight, so a real ReadAt
+// that lands on a block already being prefetched waits on that fetch
+// instead of firing a duplicate.
+type PrefetchingReaderAt struct {
+	Reader *ReaderAtHTTP
+	Cache  *MmapBlockCache
+
+	// MinWindow is the initial/minimum number of blocks prefetched ahead
+	// of a sequential access. Zero uses defaultMinPrefetchWindow.
+	MinWindow int
+
+	// MaxWindow caps how large the window can grow. Zero uses
+	// defaultMaxPrefetchWindow.
+	MaxWindow int
+
+	// MaxInFlightBytes bounds the bytes fetched by concurrent prefetches.
+	// Zero or negative means unbounded.
+	MaxInFlightBytes int64
+
+	group singleflight.Group
+	wg    sync.WaitGroup
+
+	mu        sync.Mutex
+	haveNext  bool
+	nextBlock int64
+	window    int
+
+	inFlightBytes int64 // atomic
+}
+
+// Wait blocks until all in-flight background prefetches have finished. It's
+// meant for callers (tests, or a graceful shutdown path) that need to know
+// the Cache won't be written to again before they close or reuse it.
+func (p *PrefetchingReaderAt) Wait() {
+	p.wg.Wait()
+}
+
+// ReadAt reads len(p) bytes at off, serving from the mmap cache where
+// possible and fetching (and caching) any missing blocks. It also updates
+// sequential-access tracking and kicks off background prefetch for the
+// blocks that follow.
+func (p *PrefetchingReaderAt) ReadAt(buf []byte, off int64) (int, error) {
+	size := p.Reader.Size()
+	if off < 0 {
+		return 0, io.EOF
+	}
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	end := off + int64(len(buf))
+	if end > size {
+		end = size
+	}
+
+	bs := p.Cache.BlockSize()
+	startBlock := off / bs
+	endBlock := (end - 1) / bs
+
+	p.observeAccess(startBlock, endBlock)
+
+	n := 0
+	for b := startBlock; b <= endBlock; b++ {
+		data, err := p.getBlock(b)
+		if err != nil {
+			return n, err
+		}
+
+		blockStart := b * bs
+		copyStart := off
+		if blockStart > copyStart {
+			copyStart = blockStart
+		}
+		copyEnd := end
+		if blockStart+int64(len(data)) < copyEnd {
+			copyEnd = blockStart + int64(len(data))
+		}
+		if copyEnd <= copyStart {
+			continue
+		}
+		n += copy(buf[copyStart-off:copyEnd-off], data[copyStart-blockStart:copyEnd-blockStart])
+	}
+
+	if end < off+int64(len(buf)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// observeAccess updates the sequential-access detector and, if the access
+// extended the previous run, grows the prefetch window; otherwise it
+// collapses the window back to MinWindow. It then triggers background
+// prefetch for the blocks following endBlock.
+func (p *PrefetchingReaderAt) observeAccess(startBlock, endBlock int64) {
+	minWindow := p.MinWindow
+	if minWindow <= 0 {
+		minWindow = defaultMinPrefetchWindow
+	}
+	maxWindow := p.MaxWindow
+	if maxWindow <= 0 {
+		maxWindow = defaultMaxPrefetchWindow
+	}
+
+	p.mu.Lock()
+	if p.haveNext && p.nextBlock == startBlock {
+		window := p.window * 2
+		if window < minWindow {
+			window = minWindow
+		}
+		if window > maxWindow {
+			window = maxWindow
+		}
+		p.window = window
+	} else {
+		p.window = minWindow
+	}
+	p.nextBlock = endBlock + 1
+	p.haveNext = true
+	window := p.window
+	p.mu.Unlock()
+
+	p.triggerPrefetch(endBlock+1, window)
+}
+
+// triggerPrefetch spawns background fetches for up to window blocks
+// starting at block, skipping blocks already cached and stopping once
+// MaxInFlightBytes is reserved.
+func (p *PrefetchingReaderAt) triggerPrefetch(block int64, window int) {
+	numBlocks := p.Cache.NumBlocks()
+	bs := p.Cache.BlockSize()
+
+	for i := 0; i < window; i++ {
+		b := block + int64(i)
+		if b < 0 || b >= numBlocks {
+			break
+		}
+		if _, ok := p.Cache.Get(b); ok {
+			continue
+		}
+		if !p.reserve(bs) {
+			return
+		}
+		p.wg.Add(1)
+		go func(b int64) {
+			defer p.wg.Done()
+			defer p.release(bs)
+			p.fetchBlockCoalesced(b)
+		}(b)
+	}
+}
+
+// reserve reports whether n bytes fit within MaxInFlightBytes, reserving
+// them atomically if so. MaxInFlightBytes <= 0 means unbounded.
+func (p *PrefetchingReaderAt) reserve(n int64) bool {
+	if p.MaxInFlightBytes <= 0 {
+		return true
+	}
+	for {
+		cur := atomic.LoadInt64(&p.inFlightBytes)
+		if cur+n > p.MaxInFlightBytes {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&p.inFlightBytes, cur, cur+n) {
+			return true
+		}
+	}
+}
+
+func (p *PrefetchingReaderAt) release(n int64) {
+	if p.MaxInFlightBytes > 0 {
+		atomic.AddInt64(&p.inFlightBytes, -n)
+	}
+}
+
+// getBlock returns block's data, fetching it (coalesced via singleflight
+// with any concurrent prefetch of the same block) if not cached.
+func (p *PrefetchingReaderAt) getBlock(block int64) ([]byte, error) {
+	if data, ok := p.Cache.Get(block); ok {
+		return data, nil
+	}
+	return p.fetchBlockCoalesced(block)
+}
+
+func (p *PrefetchingReaderAt) fetchBlockCoalesced(block int64) ([]byte, error) {
+	v, err, _ := p.group.Do(strconv.FormatInt(block, 10), func() (any, error) {
+		if data, ok := p.Cache.Get(block); ok {
+			return data, nil
+		}
+		return p.fetchBlock(block)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// fetchBlock issues a single Range GET for block and populates the cache.
+func (p *PrefetchingReaderAt) fetchBlock(block int64) ([]byte, error) {
+	bs := p.Cache.BlockSize()
+	start := block * bs
+	end := start + bs
+	if size := p.Reader.Size(); end > size {
+		end = size
+	}
+
+	buf := make([]byte, end-start)
+	n, err := p.Reader.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	buf = buf[:n]
+	p.Cache.Put(block, buf)
+	return buf, nil
+}
@@ -0,0 +1,146 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingTransport counts, per Range header value, the GET requests it
+// forwards, so a test can prove no block was ever fetched twice.
+type countingTransport struct {
+	inner http.RoundTripper
+	gets  int64
+
+	mu     sync.Mutex
+	ranges map[string]int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&c.gets, 1)
+	c.mu.Lock()
+	if c.ranges == nil {
+		c.ranges = make(map[string]int)
+	}
+	c.ranges[req.Header.Get("Range")]++
+	c.mu.Unlock()
+	return c.inner.RoundTrip(req)
+}
+
+func (c *countingTransport) maxRepeats() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	max := 0
+	for _, n := range c.ranges {
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+func TestPrefetchingReaderAt_SequentialAccessGrowsWindow(t *testing.T) {
+	data := make([]byte, 64*512)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	srv := serveBytesRange(data)
+	defer srv.Close()
+
+	ct := &countingTransport{inner: http.DefaultTransport}
+	ra, err := NewReaderAt(srv.URL, &http.Client{Transport: ct})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewMmapBlockCache(int64(len(data)), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	p := &PrefetchingReaderAt{Reader: ra, Cache: cache, MinWindow: 1, MaxWindow: 8}
+
+	buf := make([]byte, 512)
+	for i := 0; i < 4; i++ {
+		if _, err := p.ReadAt(buf, int64(i)*512); err != nil {
+			t.Fatalf("ReadAt %d: %v", i, err)
+		}
+	}
+	p.Wait()
+
+	if _, ok := cache.Get(7); !ok {
+		t.Fatal("expected window to have grown enough to prefetch block 7 after 4 sequential reads")
+	}
+}
+
+func TestPrefetchingReaderAt_RandomAccessResetsWindow(t *testing.T) {
+	data := make([]byte, 64*512)
+	srv := serveBytesRange(data)
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewMmapBlockCache(int64(len(data)), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	p := &PrefetchingReaderAt{Reader: ra, Cache: cache, MinWindow: 1, MaxWindow: 8}
+
+	buf := make([]byte, 512)
+	if _, err := p.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ReadAt(buf, 30*512); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Wait()
+	p.mu.Lock()
+	window := p.window
+	p.mu.Unlock()
+	if window != 1 {
+		t.Fatalf("expected window to reset to MinWindow after a non-sequential access, got %d", window)
+	}
+}
+
+func TestPrefetchingReaderAt_OverlappingReadCoalescesWithPrefetch(t *testing.T) {
+	data := make([]byte, 64*512)
+	srv := serveBytesRange(data)
+	defer srv.Close()
+
+	ct := &countingTransport{inner: http.DefaultTransport}
+	ra, err := NewReaderAt(srv.URL, &http.Client{Transport: ct})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewMmapBlockCache(int64(len(data)), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	p := &PrefetchingReaderAt{Reader: ra, Cache: cache, MinWindow: 4, MaxWindow: 4}
+
+	buf := make([]byte, 512)
+	if _, err := p.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Block 1 should now be either cached or mid-prefetch; reading it
+	// directly must not issue a second, duplicate GET for the same block.
+	if _, err := p.ReadAt(buf, 512); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Wait()
+
+	if max := ct.maxRepeats(); max > 1 {
+		t.Fatalf("expected singleflight to coalesce the overlapping read with its prefetch, but a Range was requested %d times", max)
+	}
+}
@@ -7,8 +7,9 @@ import (
 
 // Open opens a remote HTTP resource as a seekable, readable file.
 // It mirrors os.Open in spirit: the resource is opened read-only
-// and must be closed when no longer needed.
-func Open(url string) (*HTTPFile, error) {
+// and must be closed when no longer needed. Options such as WithStreaming
+// customize the returned HTTPFile.
+func Open(url string, opts ...Option) (*HTTPFile, error) {
 	client := &http.Client{
 		Transport: &CachedBlockTransport{
 			Transport: http.DefaultTransport,
@@ -19,7 +20,11 @@ func Open(url string) (*HTTPFile, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &HTTPFile{ReaderAtHTTP: ra}, nil
+	f := &HTTPFile{ReaderAtHTTP: ra}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f, nil
 }
 
 // Compile-time interface satisfaction checks
@@ -6,7 +6,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strings"
 	"sync"
 
 	"golang.org/x/sync/singleflight"
@@ -69,6 +73,10 @@ type CachedRangeTransport struct {
 }
 
 // RoundTrip implements http.RoundTripper with Range caching and validation.
+// A Range header naming several comma-separated ranges is cached and
+// coalesced per sub-range: RoundTrip fetches only the sub-ranges not
+// already cached, in a single multipart/byteranges GET, and reassembles the
+// response entirely from the cache.
 func (t *CachedRangeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	if t.Transport == nil {
 		t.Transport = http.DefaultTransport
@@ -83,6 +91,16 @@ func (t *CachedRangeTransport) RoundTrip(req *http.Request) (*http.Response, err
 		return t.Transport.RoundTrip(req)
 	}
 
+	specs := strings.Split(strings.TrimPrefix(rangeHdr, "bytes="), ",")
+	if len(specs) == 1 {
+		return t.roundTripSingle(req, rangeHdr)
+	}
+	return t.roundTripMulti(req, specs)
+}
+
+// roundTripSingle handles a request naming exactly one range, the original
+// (pre-coalescing) caching behavior.
+func (t *CachedRangeTransport) roundTripSingle(req *http.Request, rangeHdr string) (*http.Response, error) {
 	key := req.URL.String() + "|" + rangeHdr
 
 	// Try cache first.
@@ -143,3 +161,141 @@ func (t *CachedRangeTransport) RoundTrip(req *http.Request) (*http.Response, err
 	}
 	return v.(*http.Response), nil
 }
+
+// roundTripMulti handles a request naming several ranges, fetching only the
+// sub-ranges missing from the cache and reassembling the rest from it.
+func (t *CachedRangeTransport) roundTripMulti(req *http.Request, specs []string) (*http.Response, error) {
+	rangeKey := func(spec string) string {
+		return req.URL.String() + "|bytes=" + spec
+	}
+
+	missing := specs
+	if t.Cache != nil {
+		missing = make([]string, 0, len(specs))
+		for _, spec := range specs {
+			if _, ok := t.Cache.Get(rangeKey(spec)); !ok {
+				missing = append(missing, spec)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		coalesceKey := req.URL.String() + "|bytes=" + strings.Join(missing, ",")
+		if _, err, _ := t.group.Do(coalesceKey, func() (any, error) {
+			return nil, t.fetchAndCache(req, missing)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.assembleFromCache(req, specs)
+}
+
+// fetchAndCache issues a single coalesced GET for the given sub-ranges and
+// populates the cache with one entry per sub-range, parsing a
+// multipart/byteranges body when more than one range was requested.
+func (t *CachedRangeTransport) fetchAndCache(req *http.Request, specs []string) error {
+	newReq := req.Clone(req.Context())
+	newReq.Header = req.Header.Clone()
+	newReq.Header.Set("Range", "bytes="+strings.Join(specs, ","))
+
+	resp, err := t.Transport.RoundTrip(newReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("rangecache: precondition failed (HTTP 412)")
+	case http.StatusPartialContent:
+	default:
+		return fmt.Errorf("rangecache: unexpected HTTP status %s", resp.Status)
+	}
+
+	meta := FromHeaders(resp.Header)
+
+	if len(specs) == 1 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if t.Cache != nil {
+			t.Cache.Put(req.URL.String()+"|bytes="+specs[0], &CachedEntry{Data: body, Meta: meta})
+		}
+		return nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return fmt.Errorf("rangecache: expected multipart/byteranges response for multi-range request")
+	}
+
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("rangecache: reading multipart byteranges: %w", err)
+		}
+
+		start, end, ok := parseContentRange(part.Header.Get("Content-Range"))
+		data, rerr := io.ReadAll(part)
+		part.Close()
+		if rerr != nil {
+			return rerr
+		}
+		if !ok {
+			continue
+		}
+
+		spec := fmt.Sprintf("%d-%d", start, end)
+		if t.Cache != nil {
+			t.Cache.Put(req.URL.String()+"|bytes="+spec, &CachedEntry{Data: data, Meta: meta})
+		}
+	}
+	return nil
+}
+
+// assembleFromCache builds a synthetic multipart/byteranges response for
+// specs entirely out of cached entries, mirroring the wire format a server
+// would have sent had it answered the whole multi-range request itself.
+func (t *CachedRangeTransport) assembleFromCache(req *http.Request, specs []string) (*http.Response, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, spec := range specs {
+		entry, ok := t.Cache.Get(req.URL.String() + "|bytes=" + spec)
+		if !ok {
+			return nil, fmt.Errorf("rangecache: missing cache entry for range %s after fetch", spec)
+		}
+
+		hdr := textproto.MIMEHeader{}
+		hdr.Set("Content-Range", "bytes "+spec+"/*")
+		pw, err := mw.CreatePart(hdr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := pw.Write(entry.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode:    http.StatusPartialContent,
+		Status:        "206 Partial Content",
+		Body:          io.NopCloser(&buf),
+		ContentLength: int64(buf.Len()),
+		Header: http.Header{
+			"Content-Type": []string{"multipart/byteranges; boundary=" + mw.Boundary()},
+		},
+		Request:    req,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}, nil
+}
@@ -0,0 +1,139 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newRangeCacheServer serves Range GETs (single or multi-range, replying
+// multipart/byteranges for the latter) and counts the GET requests handled.
+func newRangeCacheServer(data []byte) (*httptest.Server, *int64) {
+	var gets int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&gets, 1)
+
+		rangeHdr := r.Header.Get("Range")
+		specs := splitRangeSpecs(rangeHdr)
+		if len(specs) == 1 {
+			var start, end int
+			fmt.Sscanf(specs[0], "%d-%d", &start, &end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+			return
+		}
+
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		w.WriteHeader(http.StatusPartialContent)
+		for _, spec := range specs {
+			var start, end int
+			fmt.Sscanf(spec, "%d-%d", &start, &end)
+			part, _ := mw.CreatePart(map[string][]string{
+				"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", start, end, len(data))},
+			})
+			part.Write(data[start : end+1])
+		}
+		mw.Close()
+	})
+	return httptest.NewServer(mux), &gets
+}
+
+func splitRangeSpecs(rangeHdr string) []string {
+	rangeHdr = rangeHdr[len("bytes="):]
+	var specs []string
+	start := 0
+	for i := 0; i < len(rangeHdr); i++ {
+		if rangeHdr[i] == ',' {
+			specs = append(specs, rangeHdr[start:i])
+			start = i + 1
+		}
+	}
+	return append(specs, rangeHdr[start:])
+}
+
+func doRange(t *testing.T, client *http.Client, url, rangeHdr string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Range", rangeHdr)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func readMultipartRanges(t *testing.T, resp *http.Response) map[string][]byte {
+	t.Helper()
+	defer resp.Body.Close()
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("expected multipart/byteranges, got %q (err %v)", resp.Header.Get("Content-Type"), err)
+	}
+
+	out := make(map[string][]byte)
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		start, end, ok := parseContentRange(part.Header.Get("Content-Range"))
+		if !ok {
+			continue
+		}
+		data := make([]byte, end-start+1)
+		part.Read(data)
+		out[fmt.Sprintf("%d-%d", start, end)] = data
+	}
+	return out
+}
+
+func TestCachedRangeTransport_MultiRangeCoalescesMisses(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	srv, gets := newRangeCacheServer(data)
+	defer srv.Close()
+
+	tr := &CachedRangeTransport{Transport: http.DefaultTransport, Cache: NewMemoryCache()}
+	client := &http.Client{Transport: tr}
+
+	resp := doRange(t, client, srv.URL, "bytes=0-3,10-12")
+	parts := readMultipartRanges(t, resp)
+	if !bytes.Equal(parts["0-3"], data[0:4]) || !bytes.Equal(parts["10-12"], data[10:13]) {
+		t.Fatalf("unexpected first response: %v", parts)
+	}
+	if got := atomic.LoadInt64(gets); got != 1 {
+		t.Fatalf("expected 1 origin GET, got %d", got)
+	}
+
+	// A second request reusing one of the cached ranges alongside a new one
+	// should only fetch the new one.
+	resp = doRange(t, client, srv.URL, "bytes=0-3,20-22")
+	parts = readMultipartRanges(t, resp)
+	if !bytes.Equal(parts["0-3"], data[0:4]) || !bytes.Equal(parts["20-22"], data[20:23]) {
+		t.Fatalf("unexpected second response: %v", parts)
+	}
+	if got := atomic.LoadInt64(gets); got != 2 {
+		t.Fatalf("expected 2 total origin GETs after partial cache hit, got %d", got)
+	}
+
+	// A third, fully-cached request should not touch the origin at all.
+	resp = doRange(t, client, srv.URL, "bytes=0-3,20-22")
+	readMultipartRanges(t, resp)
+	if got := atomic.LoadInt64(gets); got != 2 {
+		t.Fatalf("expected no new origin GET for a fully cached multi-range request, got %d", got)
+	}
+}
@@ -0,0 +1,339 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// ReaderAtHTTP implements io.ReaderAt for HTTP URLs using Range requests.
+type ReaderAtHTTP struct {
+	client *http.Client
+	url    string
+	meta   Metadata
+
+	// Retry configures retry-with-resume for transient failures in
+	// ReadAtContext. Nil (the default) disables retries.
+	Retry *RetryPolicy
+
+	// Mode controls how freshness is enforced against the resource's
+	// ETag/Last-Modified/length, captured at NewReaderAt. Zero value is
+	// RevalidateStrong.
+	Mode RevalidateMode
+}
+
+// RevalidateMode controls how a ReaderAtHTTP enforces that the remote
+// resource hasn't changed since it was opened.
+type RevalidateMode int
+
+const (
+	// RevalidateStrong sends If-Match/If-Unmodified-Since on every range
+	// GET, so the server itself rejects a changed resource with 412
+	// Precondition Failed. This is the default.
+	RevalidateStrong RevalidateMode = iota
+
+	// RevalidateLax omits conditional headers on ordinary reads; freshness
+	// is only checked passively against each response's own headers, or
+	// on demand via Revalidate.
+	RevalidateLax
+)
+
+// ErrResourceChanged is returned from ReadAt/ReadAtContext/Revalidate when
+// the remote resource's ETag, Last-Modified, or length no longer matches
+// what NewReaderAt observed, so a caller can decide whether to reopen
+// instead of silently stitching together stale and fresh bytes.
+var ErrResourceChanged = errors.New("httpseek: resource changed")
+
+// Revalidate issues a fresh HEAD request and reports ErrResourceChanged if
+// the resource's validators no longer match those captured at open time.
+func (r *ReaderAtHTTP) Revalidate() error {
+	req, err := http.NewRequest(http.MethodHead, r.url, nil)
+	if err != nil {
+		return err
+	}
+
+	start := logRequest(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	logResponse(resp, start)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("httpseek: HEAD %s returned %s", r.url, resp.Status)
+	}
+	if !r.meta.Equal(extractMetadata(resp.Header)) {
+		return ErrResourceChanged
+	}
+	return nil
+}
+
+// Range describes an inclusive byte range [Start, End] of a remote resource.
+type Range struct {
+	Start, End int64
+}
+
+// NewReaderAt creates a ReaderAtHTTP. If client is nil, http.DefaultClient is used.
+func NewReaderAt(url string, client *http.Client) (*ReaderAtHTTP, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	start := logRequest(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	logResponse(resp, start)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("httpseek: HEAD %s returned %s", url, resp.Status)
+	}
+
+	meta := extractMetadata(resp.Header)
+	if meta.Length <= 0 {
+		return nil, fmt.Errorf("httpseek: missing Content-Length")
+	}
+
+	if !strings.Contains(resp.Header.Get("Accept-Ranges"), "bytes") {
+		return nil, errors.New("httpseek: server does not accept bytes ranges")
+	}
+
+	return &ReaderAtHTTP{
+		client: client,
+		url:    url,
+		meta:   meta,
+	}, nil
+}
+
+// Size returns the remote resource size in bytes.
+func (r *ReaderAtHTTP) Size() int64 { return r.meta.Length }
+
+// Close is a no-op, present for interface compatibility.
+func (r *ReaderAtHTTP) Close() error { return nil }
+
+// ReadAt reads len(p) bytes starting at off using a single Range GET.
+// It does not affect any other state and is safe for concurrent use.
+func (r *ReaderAtHTTP) ReadAt(p []byte, off int64) (int, error) {
+	return r.ReadAtContext(context.Background(), p, off)
+}
+
+// ReadAtContext is like ReadAt but honors ctx for cancellation and deadlines.
+// When Retry is set, transient failures (timeouts, 5xx, 408/429) are retried
+// with exponential backoff, resuming from the last byte successfully read
+// rather than re-fetching the whole range.
+func (r *ReaderAtHTTP) ReadAtContext(ctx context.Context, p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("httpseek: invalid offset")
+	}
+	if off >= r.meta.Length {
+		return 0, io.EOF
+	}
+
+	end := off + int64(len(p)) - 1
+	if end >= r.meta.Length {
+		end = r.meta.Length - 1
+	}
+
+	retry := r.Retry
+	var read int
+	for attempt := 0; ; attempt++ {
+		n, err := r.readRange(ctx, p[read:end-off+1], off+int64(read), end)
+		read += n
+		if err == nil || errors.Is(err, io.EOF) {
+			return read, err
+		}
+		if retry == nil || !retry.shouldRetry(attempt, err) {
+			return read, err
+		}
+		if int64(read) > end-off {
+			return read, nil
+		}
+		if werr := retry.wait(ctx, attempt, retryAfter(err)); werr != nil {
+			return read, werr
+		}
+	}
+}
+
+// readRange issues a single Range GET for [off, end] into p, applying the
+// resource's current validators so a mid-stream change surfaces as a 412
+// instead of silently stitching together stale and fresh bytes.
+func (r *ReaderAtHTTP) readRange(ctx context.Context, p []byte, off, end int64) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, end))
+	if r.Mode == RevalidateStrong {
+		r.meta.ApplyValidators(req.Header)
+	}
+
+	start := logRequest(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	logResponse(resp, start)
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent, http.StatusOK:
+	case http.StatusPreconditionFailed:
+		return 0, ErrResourceChanged
+	default:
+		return 0, &statusError{resp: resp}
+	}
+
+	if !r.meta.Equal(extractMetadata(resp.Header)) {
+		return 0, ErrResourceChanged
+	}
+
+	// io.ReadFull reports a short read as io.ErrUnexpectedEOF, distinct from
+	// the clean io.EOF it returns when zero bytes were read; that
+	// distinction must survive so ReadAtContext's retry/resume logic (and
+	// DefaultShouldRetry's explicit ErrUnexpectedEOF case) can tell a
+	// genuine mid-range truncation from reaching the end of the range.
+	return io.ReadFull(resp.Body, p)
+}
+
+// ReadRanges fetches multiple byte ranges in as few round-trips as possible.
+// It issues a single request with a comma-joined Range header
+// (bytes=a1-b1,a2-b2,...) and, when the server replies with a 206 carrying
+// a multipart/byteranges body, parses each MIME part with mime/multipart and
+// matches it back to the requested interval via its Content-Range header.
+// If the server collapses the ranges (a 200, or a single-part 206), or
+// otherwise does not honor multipart, ReadRanges falls back to one
+// sequential ReadAt per range.
+func (r *ReaderAtHTTP) ReadRanges(ranges []Range) ([][]byte, error) {
+	return r.ReadRangesContext(context.Background(), ranges)
+}
+
+// ReadRangesContext is like ReadRanges but honors ctx for cancellation and
+// deadlines.
+func (r *ReaderAtHTTP) ReadRangesContext(ctx context.Context, ranges []Range) ([][]byte, error) {
+	if len(ranges) == 0 {
+		return nil, nil
+	}
+	if len(ranges) == 1 {
+		return r.readRangesSequential(ctx, ranges)
+	}
+
+	parts := make([]string, len(ranges))
+	for i, rg := range ranges {
+		end := rg.End
+		if end >= r.meta.Length {
+			end = r.meta.Length - 1
+		}
+		parts[i] = fmt.Sprintf("%d-%d", rg.Start, end)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", "bytes="+strings.Join(parts, ","))
+	r.meta.ApplyValidators(req.Header)
+
+	start := logRequest(req)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	logResponse(resp, start)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Server ignored the Range header entirely; fall back.
+		return r.readRangesSequential(ctx, ranges)
+	case http.StatusPartialContent:
+	default:
+		return nil, fmt.Errorf("httpseek: unexpected HTTP status %s", resp.Status)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		// Server collapsed the ranges into a single part.
+		return r.readRangesSequential(ctx, ranges)
+	}
+
+	out := make([][]byte, len(ranges))
+	found := make([]bool, len(ranges))
+
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("httpseek: reading multipart byteranges: %w", err)
+		}
+
+		start, end, ok := parseContentRange(part.Header.Get("Content-Range"))
+		if !ok {
+			part.Close()
+			continue
+		}
+
+		data, err := io.ReadAll(part)
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for i, rg := range ranges {
+			wantEnd := rg.End
+			if wantEnd >= r.meta.Length {
+				wantEnd = r.meta.Length - 1
+			}
+			if rg.Start == start && wantEnd == end {
+				out[i] = data
+				found[i] = true
+			}
+		}
+	}
+
+	for i, ok := range found {
+		if !ok {
+			return nil, fmt.Errorf("httpseek: server did not return requested range %d-%d", ranges[i].Start, ranges[i].End)
+		}
+	}
+	return out, nil
+}
+
+// readRangesSequential fetches each range with its own ReadAtContext call.
+func (r *ReaderAtHTTP) readRangesSequential(ctx context.Context, ranges []Range) ([][]byte, error) {
+	out := make([][]byte, len(ranges))
+	for i, rg := range ranges {
+		buf := make([]byte, rg.End-rg.Start+1)
+		n, err := r.ReadAtContext(ctx, buf, rg.Start)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		out[i] = buf[:n]
+	}
+	return out, nil
+}
@@ -0,0 +1,147 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveMultipartRanges serves Range requests and supports multi-range
+// requests by replying with a multipart/byteranges body.
+func serveMultipartRanges(data []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.WriteHeader(http.StatusOK)
+			return
+		case http.MethodGet:
+		default:
+			http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+			return
+		}
+
+		rangeHdr := strings.TrimPrefix(r.Header.Get("Range"), "bytes=")
+		specs := strings.Split(rangeHdr, ",")
+		if len(specs) == 1 {
+			var start, end int
+			fmt.Sscanf(specs[0], "%d-%d", &start, &end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+			return
+		}
+
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		w.WriteHeader(http.StatusPartialContent)
+		for _, spec := range specs {
+			var start, end int
+			fmt.Sscanf(spec, "%d-%d", &start, &end)
+			part, _ := mw.CreatePart(map[string][]string{
+				"Content-Range": {fmt.Sprintf("bytes %d-%d/%d", start, end, len(data))},
+			})
+			part.Write(data[start : end+1])
+		}
+		mw.Close()
+	}))
+}
+
+func TestReadRangesMultipart(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	srv := serveMultipartRanges(data)
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+
+	got, err := ra.ReadRanges([]Range{{Start: 0, End: 3}, {Start: 10, End: 12}})
+	if err != nil {
+		t.Fatalf("ReadRanges: %v", err)
+	}
+	if !bytes.Equal(got[0], data[0:4]) {
+		t.Fatalf("range 0: got %q want %q", got[0], data[0:4])
+	}
+	if !bytes.Equal(got[1], data[10:13]) {
+		t.Fatalf("range 1: got %q want %q", got[1], data[10:13])
+	}
+}
+
+func TestReadRangesContextCancellation(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	srv := serveMultipartRanges(data)
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ra.ReadRangesContext(ctx, []Range{{Start: 0, End: 3}, {Start: 10, End: 12}}); err == nil {
+		t.Fatal("expected error from a canceled context")
+	}
+}
+
+func TestReadRangesFallsBackToSequential(t *testing.T) {
+	data := []byte("0123456789")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			var start, end int
+			fmt.Sscanf(strings.Split(r.Header.Get("Range"), ",")[0], "bytes=%d-%d", &start, &end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+		}
+	}))
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+
+	got, err := ra.ReadRanges([]Range{{Start: 0, End: 1}, {Start: 5, End: 6}})
+	if err != nil {
+		t.Fatalf("ReadRanges: %v", err)
+	}
+	if !bytes.Equal(got[0], data[0:2]) || !bytes.Equal(got[1], data[5:7]) {
+		t.Fatalf("unexpected ranges: %q %q", got[0], got[1])
+	}
+}
+
+func TestReadRangesSingleRangeUsesPlainGET(t *testing.T) {
+	data := []byte("hello world")
+	srv := serveBytesRange(data)
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+
+	got, err := ra.ReadRanges([]Range{{Start: 0, End: 4}})
+	if err != nil {
+		t.Fatalf("ReadRanges: %v", err)
+	}
+	if !bytes.Equal(got[0], data[0:5]) {
+		t.Fatalf("got %q want %q", got[0], data[0:5])
+	}
+}
@@ -16,6 +16,12 @@ type HTTPFile struct {
 	*ReaderAtHTTP
 	offset int64
 	mu     sync.Mutex
+
+	// streaming mode state; see WithStreaming.
+	streaming            bool
+	forwardSkipThreshold int64
+	body                 io.ReadCloser
+	bodyPos              int64
 }
 
 // NewReadSeeker wraps an existing ReaderAtHTTP.
@@ -23,16 +29,32 @@ func NewReadSeeker(r *ReaderAtHTTP) *HTTPFile {
 	return &HTTPFile{ReaderAtHTTP: r}
 }
 
-// Read reads from the current offset and advances it.
+// Read reads from the current offset and advances it. In streaming mode
+// (see WithStreaming) this consumes a persistent open-ended response
+// instead of issuing a fresh bounded Range GET per call.
 func (r *HTTPFile) Read(p []byte) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if r.streaming {
+		return r.streamRead(p)
+	}
+
 	n, err := r.ReadAt(p, r.offset)
 	r.offset += int64(n)
 	return n, err
 }
 
+// Close releases any resources held by the file, including an open
+// streaming response body, and closes the underlying ReaderAtHTTP.
+func (r *HTTPFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closeStream()
+	return r.ReaderAtHTTP.Close()
+}
+
 // Seek implements io.Seeker.
 func (r *HTTPFile) Seek(offset int64, whence int) (int64, error) {
 	r.mu.Lock()
@@ -0,0 +1,170 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures retry-with-resume behavior for transient failures
+// on ReaderAtHTTP.ReadAtContext, such as flaky networks during large
+// sequential downloads of ISOs or container layers.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of retries after the first attempt.
+	// Zero means no retries.
+	MaxAttempts int
+
+	// BaseBackoff is the delay before the first retry; it doubles on each
+	// subsequent attempt up to MaxBackoff.
+	BaseBackoff time.Duration
+
+	// MaxBackoff caps the computed backoff delay.
+	MaxBackoff time.Duration
+
+	// ShouldRetry classifies an error as retryable. If nil, DefaultShouldRetry
+	// is used.
+	ShouldRetry func(err error) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy with sensible defaults:
+// up to 5 retries with exponential backoff between 500ms and 30s.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 5,
+		BaseBackoff: 500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+	}
+}
+
+// WithRetry attaches a retry policy to the HTTPFile returned by Open, so
+// ReadAt/Read transparently retry transient failures (timeouts, 408/429/5xx)
+// with exponential backoff, resuming from the last byte successfully read.
+// A nil policy disables retries, which is also the default. Read in
+// streaming mode (see WithStreaming) does not retry.
+func WithRetry(policy *RetryPolicy) Option {
+	return func(f *HTTPFile) {
+		f.Retry = policy
+	}
+}
+
+// statusError wraps a non-2xx/206 HTTP response so the retry classifier can
+// inspect the status code and Retry-After header.
+type statusError struct {
+	resp *http.Response
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("httpseek: unexpected HTTP status %s", e.resp.Status)
+}
+
+// DefaultShouldRetry retries on timeouts, temporary network errors,
+// truncated bodies, and 408/429/5xx responses.
+func DefaultShouldRetry(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && (netErr.Timeout() || isTemporary(netErr)) {
+		return true
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		switch se.resp.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests,
+			http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// isTemporary reports err.Temporary() for net.Error implementations that
+// still support the deprecated method, without failing to build on those
+// that don't.
+func isTemporary(err net.Error) bool {
+	type temporary interface{ Temporary() bool }
+	if t, ok := err.(temporary); ok {
+		return t.Temporary()
+	}
+	return false
+}
+
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	classify := p.ShouldRetry
+	if classify == nil {
+		classify = DefaultShouldRetry
+	}
+	return classify(err)
+}
+
+func (p *RetryPolicy) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = p.backoff(attempt)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base := p.BaseBackoff
+	if base <= 0 {
+		base = DefaultRetryPolicy().BaseBackoff
+	}
+	max := p.MaxBackoff
+	if max <= 0 {
+		max = DefaultRetryPolicy().MaxBackoff
+	}
+	d := base << attempt
+	if d <= 0 || d > max {
+		d = max
+	}
+	// Add up to 20% jitter to avoid thundering-herd retries.
+	jitter := time.Duration(rand.Int63n(int64(d)/5 + 1))
+	return d + jitter
+}
+
+// retryAfter extracts a server-requested delay from a 429/503 response's
+// Retry-After header, if present. It supports both the delta-seconds and
+// HTTP-date forms.
+func retryAfter(err error) time.Duration {
+	var se *statusError
+	if !errors.As(err, &se) {
+		return 0
+	}
+	if se.resp.StatusCode != http.StatusTooManyRequests && se.resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+	v := se.resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
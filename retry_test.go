@@ -0,0 +1,246 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadAtContextRetriesTransientFailure(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	var hits int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			if atomic.AddInt64(&hits, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			var start, end int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+		}
+	}))
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+	ra.Retry = &RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+	buf := make([]byte, 4)
+	n, err := ra.ReadAt(buf, 0)
+	if err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if string(buf[:n]) != "0123" {
+		t.Fatalf("got %q want %q", buf[:n], "0123")
+	}
+	if atomic.LoadInt64(&hits) != 2 {
+		t.Fatalf("expected 2 GET attempts, got %d", hits)
+	}
+}
+
+func TestHTTPFile_WithRetryRetriesTransientFailure(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	var hits int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			if atomic.AddInt64(&hits, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			var start, end int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+		}
+	}))
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+	f := NewReadSeeker(ra)
+	WithRetry(&RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})(f)
+
+	buf := make([]byte, 4)
+	n, err := f.Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(buf[:n]) != "0123" {
+		t.Fatalf("got %q want %q", buf[:n], "0123")
+	}
+	if atomic.LoadInt64(&hits) != 2 {
+		t.Fatalf("expected 2 GET attempts, got %d", hits)
+	}
+}
+
+func TestReadAtContextGivesUpAfterMaxAttempts(t *testing.T) {
+	data := []byte("0123456789")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+	ra.Retry = &RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	buf := make([]byte, 4)
+	if _, err := ra.ReadAt(buf, 0); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+}
+
+func TestReadAtContextResumesAfterTruncatedBody(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	var hits int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			var start, end int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			if atomic.AddInt64(&hits, 1) == 1 {
+				// Promise the full range via Content-Length but only write
+				// the first byte, then drop the connection: io.ReadFull
+				// on the client sees io.ErrUnexpectedEOF, not a clean EOF.
+				w.Header().Set("Content-Length", fmt.Sprintf("%d", end-start+1))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(data[start : start+1])
+				return
+			}
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+		}
+	}))
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+	ra.Retry = &RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+	buf := make([]byte, 4)
+	n, err := ra.ReadAt(buf, 0)
+	if err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if string(buf[:n]) != "0123" {
+		t.Fatalf("got %q want %q", buf[:n], "0123")
+	}
+	if atomic.LoadInt64(&hits) != 2 {
+		t.Fatalf("expected a retry after the truncated body, got %d GET attempts", hits)
+	}
+}
+
+func TestCachedBlockTransport_RetriesTransientFailure(t *testing.T) {
+	data := bytesRepeat("0123456789abcdef", 32)
+	var hits int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var start, end int
+		fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     NewMemoryBlockCache(),
+			BlockSize: 16,
+			Retry:     &RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond},
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-3")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "0123" {
+		t.Fatalf("got %q want %q", body, "0123")
+	}
+	if atomic.LoadInt64(&hits) != 2 {
+		t.Fatalf("expected 2 GET attempts, got %d", hits)
+	}
+}
+
+func TestCachedBlockTransport_GivesUpAfterMaxAttempts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{
+		Transport: &CachedBlockTransport{
+			Transport: http.DefaultTransport,
+			Cache:     NewMemoryBlockCache(),
+			BlockSize: 16,
+			Retry:     &RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond},
+		},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-3")
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+}
+
+func bytesRepeat(s string, n int) []byte {
+	out := make([]byte, 0, len(s)*n)
+	for i := 0; i < n; i++ {
+		out = append(out, s...)
+	}
+	return out
+}
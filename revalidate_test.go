@@ -0,0 +1,88 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// mutatingServer serves a HEAD/GET pair whose ETag flips to "v2" once at
+// least changeAfter requests (of any method) have already been served.
+func mutatingServer(data []byte, changeAfter int64) *httptest.Server {
+	var reqs int64
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"v1"`
+		if atomic.LoadInt64(&reqs) >= changeAfter {
+			etag = `"v2"`
+		}
+		atomic.AddInt64(&reqs, 1)
+
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			var start, end int
+			fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+		}
+	}))
+}
+
+func TestReadAtDetectsResourceChange(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	srv := mutatingServer(data, 2)
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+
+	buf := make([]byte, 4)
+	if _, err := ra.ReadAt(buf, 0); err != nil {
+		t.Fatalf("first ReadAt: %v", err)
+	}
+	if _, err := ra.ReadAt(buf, 4); err != ErrResourceChanged {
+		t.Fatalf("expected ErrResourceChanged, got %v", err)
+	}
+}
+
+func TestRevalidateDetectsChange(t *testing.T) {
+	data := []byte("0123456789")
+	srv := mutatingServer(data, 1)
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+
+	if err := ra.Revalidate(); err != ErrResourceChanged {
+		t.Fatalf("expected ErrResourceChanged, got %v", err)
+	}
+}
+
+func TestRevalidateNoChange(t *testing.T) {
+	data := []byte("0123456789")
+	srv := serveBytesRange(data)
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewReaderAt: %v", err)
+	}
+
+	if err := ra.Revalidate(); err != nil {
+		t.Fatalf("expected no change, got %v", err)
+	}
+}
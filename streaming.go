@@ -0,0 +1,117 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Option configures an HTTPFile at construction time, for use with Open.
+type Option func(*HTTPFile)
+
+// WithStreaming enables streaming read mode: instead of issuing one bounded
+// Range GET per Read, the file keeps a single open-ended response
+// (Range: bytes=off-) open and consumes it sequentially, which preserves
+// HTTP keep-alive for mostly-forward readers such as tar, zip, and ffmpeg.
+// The stream is torn down and reopened when the read position moves behind
+// the stream, or more than threshold bytes ahead of it; a forward seek
+// within threshold instead discards bytes from the open body. ReadAt never
+// disturbs the stream. The zero threshold reopens on any non-contiguous
+// read.
+func WithStreaming(threshold int64) Option {
+	return func(f *HTTPFile) {
+		f.streaming = true
+		f.forwardSkipThreshold = threshold
+	}
+}
+
+// streamRead services a streaming Read from the current offset, opening or
+// reusing the underlying response body as needed.
+func (f *HTTPFile) streamRead(p []byte) (int, error) {
+	if f.offset >= f.Size() {
+		return 0, io.EOF
+	}
+
+	if f.body != nil {
+		switch skip := f.offset - f.bodyPos; {
+		case skip == 0:
+		case skip > 0 && skip <= f.forwardSkipThreshold:
+			if _, err := io.CopyN(io.Discard, f.body, skip); err != nil {
+				f.closeStream()
+			} else {
+				f.bodyPos += skip
+			}
+		default:
+			f.closeStream()
+		}
+	}
+
+	if f.body == nil {
+		if err := f.openStream(f.offset); err != nil {
+			return 0, err
+		}
+	}
+
+	if max := f.Size() - f.offset; int64(len(p)) > max {
+		p = p[:max]
+	}
+
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	f.bodyPos += int64(n)
+	if err == io.EOF {
+		f.closeStream()
+		if n > 0 {
+			err = nil
+		}
+	} else if err != nil {
+		f.closeStream()
+	}
+	return n, err
+}
+
+// openStream issues an open-ended Range GET starting at off and keeps the
+// response body open for subsequent streamRead calls.
+func (f *HTTPFile) openStream(off int64) error {
+	req, err := http.NewRequest(http.MethodGet, f.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", off))
+	if f.Mode == RevalidateStrong {
+		f.meta.ApplyValidators(req.Header)
+	}
+
+	start := logRequest(req)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	logResponse(resp, start)
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent, http.StatusOK:
+	case http.StatusPreconditionFailed:
+		resp.Body.Close()
+		return ErrResourceChanged
+	default:
+		resp.Body.Close()
+		return &statusError{resp: resp}
+	}
+
+	f.body = resp.Body
+	f.bodyPos = off
+	return nil
+}
+
+// closeStream releases the open streaming body, if any.
+func (f *HTTPFile) closeStream() {
+	if f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+}
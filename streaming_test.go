@@ -0,0 +1,188 @@
+/* SPDX-License-Identifier: BSD-2-Clause */
+
+package httpseek
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newStreamingServer serves HEAD and both bounded and open-ended
+// (bytes=start-) Range GETs, counting the number of GET requests handled.
+func newStreamingServer(data []byte) (*httptest.Server, *int64) {
+	var gets int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			atomic.AddInt64(&gets, 1)
+			rangeHdr := r.Header.Get("Range")
+			var start, end int
+			if n, _ := fmt.Sscanf(rangeHdr, "bytes=%d-%d", &start, &end); n != 2 {
+				fmt.Sscanf(rangeHdr, "bytes=%d-", &start)
+				end = len(data) - 1
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+		default:
+			http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+		}
+	}))
+	return srv, &gets
+}
+
+func TestHTTPFile_StreamingSequentialReadIsSingleRequest(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	srv, gets := newStreamingServer(data)
+	defer srv.Close()
+
+	ra, err := NewReaderAt(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewReadSeeker(ra)
+	WithStreaming(4)(f)
+	defer f.Close()
+
+	buf := make([]byte, 3)
+	var total []byte
+	for {
+		n, err := f.Read(buf)
+		total = append(total, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+	}
+
+	if string(total) != string(data) {
+		t.Fatalf("got %q want %q", total, data)
+	}
+	if got := atomic.LoadInt64(gets); got != 1 {
+		t.Fatalf("expected 1 GET for a fully sequential read, got %d", got)
+	}
+}
+
+func TestHTTPFile_StreamingSmallForwardSeekReusesStream(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	srv, gets := newStreamingServer(data)
+	defer srv.Close()
+
+	ra, _ := NewReaderAt(srv.URL, nil)
+	f := NewReadSeeker(ra)
+	WithStreaming(4)(f)
+	defer f.Close()
+
+	buf := make([]byte, 2)
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	// Skip forward by 3 bytes, within the threshold of 4.
+	if _, err := f.Seek(3, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt64(gets); got != 1 {
+		t.Fatalf("expected stream to be reused across a small forward seek, got %d GETs", got)
+	}
+}
+
+func TestHTTPFile_StreamingLargeForwardSeekReopens(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	srv, gets := newStreamingServer(data)
+	defer srv.Close()
+
+	ra, _ := NewReaderAt(srv.URL, nil)
+	f := NewReadSeeker(ra)
+	WithStreaming(2)(f)
+	defer f.Close()
+
+	buf := make([]byte, 2)
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	// Skip forward by 10 bytes, well beyond the threshold of 2.
+	if _, err := f.Seek(10, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt64(gets); got != 2 {
+		t.Fatalf("expected stream to reopen after a large forward seek, got %d GETs", got)
+	}
+}
+
+func TestHTTPFile_StreamingBackwardSeekReopens(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	srv, gets := newStreamingServer(data)
+	defer srv.Close()
+
+	ra, _ := NewReaderAt(srv.URL, nil)
+	f := NewReadSeeker(ra)
+	WithStreaming(4)(f)
+	defer f.Close()
+
+	buf := make([]byte, 4)
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(-2, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt64(gets); got != 2 {
+		t.Fatalf("expected stream to reopen after a backward seek, got %d GETs", got)
+	}
+}
+
+func TestHTTPFile_StreamingReadAtDoesNotDisturbStream(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	srv, gets := newStreamingServer(data)
+	defer srv.Close()
+
+	ra, _ := NewReaderAt(srv.URL, nil)
+	f := NewReadSeeker(ra)
+	WithStreaming(4)(f)
+	defer f.Close()
+
+	buf := make([]byte, 4)
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unrelated ReadAt elsewhere in the file must not tear down the
+	// sequential stream.
+	other := make([]byte, 2)
+	if _, err := f.ReadAt(other, 15); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt64(gets); got != 2 {
+		t.Fatalf("expected exactly 2 GETs (1 stream + 1 ReadAt), got %d", got)
+	}
+	if f.body == nil {
+		t.Fatal("expected streaming body to remain open after an unrelated ReadAt")
+	}
+}
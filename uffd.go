@@ -1,18 +1,26 @@
 // SPDX-License-Identifier: BSD-2-Clause
+
+//go:build linux
+
 package httpseek
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"log"
+	"sync"
 	"unsafe"
 
 	uffd "github.com/ricardobranco777/go-userfaultfd"
 	"golang.org/x/sys/unix"
 )
 
-// UffdHTTPReader maps a remote HTTP file into memory and faults pages on demand.
+// UffdHTTPReader maps a remote HTTP file into memory and faults pages on
+// demand using Linux's userfaultfd. See uffd_common.go for the
+// platform-independent fault-batching and prefetch logic shared with the
+// non-Linux fallback in uffd_fallback.go; this file provides that shared
+// logic's "install" backend: registering the mapping with userfaultfd and
+// resolving faults via Uffd.Copy/Uffd.Zeropage.
 type UffdHTTPReader struct {
 	File     *HTTPFile
 	Uffd     *uffd.Uffd
@@ -25,17 +33,53 @@ type UffdHTTPReader struct {
 	pos    int64   // read offset for io.Reader
 
 	done chan struct{}
-}
 
-// Ensure interface sanity
-var (
-	_ io.Closer = (*UffdHTTPReader)(nil)
-	_ io.Reader = (*UffdHTTPReader)(nil)
-)
+	// MaxCoalesceGap is the largest gap, in pages, between two pages in a
+	// fault batch that still get folded into a single contiguous Range,
+	// pulling in the (not yet faulting) pages between them rather than
+	// paying for a second round trip later. Zero uses
+	// defaultMaxCoalesceGap.
+	MaxCoalesceGap int
+
+	// MaxRangesPerRequest caps how many byte ranges go into a single
+	// Range header; some servers refuse a multipart/byteranges response
+	// with too many parts. Zero uses defaultMaxRangesPerRequest.
+	MaxRangesPerRequest int
+
+	// ReadAheadPages extends a fault batch past its highest page by this
+	// many additional pages, speculatively filling them before they
+	// fault. Zero disables read-ahead.
+	ReadAheadPages int
+
+	// PrefetchWindow is how many recent fault page indices handlePageFault
+	// tracks to detect a monotonic access stride. Zero uses
+	// defaultPrefetchWindow.
+	PrefetchWindow int
+
+	// PrefetchAhead is how many pages past a detected monotonic stride get
+	// speculatively fetched and pre-satisfied via Uffd.Copy in the
+	// background, before the mutator ever faults on them. Zero disables
+	// background prefetch.
+	PrefetchAhead int
+
+	// PrefetchWorkers bounds how many background prefetches can run
+	// concurrently, so a fast sequential scan doesn't flood the origin
+	// with unbounded parallel requests. Zero uses defaultUffdPrefetchWorkers.
+	PrefetchWorkers int
 
-// roundUp rounds n up to a multiple of align (align must be power of 2).
-func roundUp(n, align int) int {
-	return (n + align - 1) &^ (align - 1)
+	pageState []int32 // atomic per-page state: pageUnfetched/pagePending/pageDone
+
+	historyMu sync.Mutex
+	history   []int64 // ring buffer of recent fault page indices
+
+	prefetchSemOnce sync.Once
+	prefetchSem     chan struct{}
+
+	statsMu sync.Mutex
+	stats   PrefetchStats
+
+	errMu sync.Mutex
+	err   error // first fatal fetch error, surfaced by Read instead of crashing
 }
 
 // NewUffdHTTPReader maps a remote HTTP file using userfaultfd.
@@ -72,15 +116,16 @@ func NewUffdHTTPReader(f *HTTPFile) (*UffdHTTPReader, error) {
 	}
 
 	r := &UffdHTTPReader{
-		File:     f,
-		Uffd:     u,
-		full:     full,
-		data:     full[:n], // visible file content slice
-		PageSize: pageSize,
-		base:     base,
-		mapLen:   mapLen,
-		pos:      0,
-		done:     make(chan struct{}),
+		File:      f,
+		Uffd:      u,
+		full:      full,
+		data:      full[:n], // visible file content slice
+		PageSize:  pageSize,
+		base:      base,
+		mapLen:    mapLen,
+		pos:       0,
+		done:      make(chan struct{}),
+		pageState: make([]int32, mapLen/pageSize),
 	}
 
 	// Register the full page-aligned region.
@@ -113,78 +158,64 @@ func (r *UffdHTTPReader) faultLoop() {
 		switch msg.Event {
 		case uffd.UFFD_EVENT_PAGEFAULT:
 			pf := msg.GetPagefault()
-			r.handlePageFault(pf)
+			r.handlePageFault(uintptr(pf.Address))
 		default:
 			log.Printf("httpseek: unexpected uffd event 0x%x", msg.Event)
 		}
 	}
 }
 
-func (r *UffdHTTPReader) handlePageFault(pf *uffd.UffdMsgPagefault) {
-	faultAddr := uintptr(pf.Address)
-
-	// Page-align the fault address.
-	pageSize := r.PageSize
-	pageMask := uintptr(pageSize - 1)
-	pageAddr := faultAddr &^ pageMask
-
-	// Compute page index relative to the start of the mapping.
-	if pageAddr < r.base {
-		log.Printf("httpseek: page fault before base: addr=0x%x base=0x%x", faultAddr, r.base)
-		return
-	}
-	pageIndex := (pageAddr - r.base) / uintptr(pageSize)
-
-	// Compute file offset in bytes.
-	fileOffset := int64(pageIndex) * int64(pageSize)
-
-	// Safety check against mapped region.
-	if fileOffset < 0 || fileOffset >= int64(r.mapLen) {
-		log.Printf("httpseek: page fault out of mapped range: addr=0x%x idx=%d off=%d", faultAddr, pageIndex, fileOffset)
-		return
-	}
-
-	buf := make([]byte, pageSize)
-
-	// How much of this page is actually in the file?
-	fileSize := r.File.Size()
-	if fileOffset >= fileSize {
-		// Completely beyond EOF: leave buf zero-filled.
-	} else {
-		// Clamp read to not cross EOF.
-		toRead := int64(pageSize)
-		if fileOffset+toRead > fileSize {
-			toRead = fileSize - fileOffset
+// drainPendingFaults non-blockingly drains any other UFFD_EVENT_PAGEFAULT
+// messages already queued on the userfaultfd, so a burst of faults from a
+// random-access workload gets folded into the same batch instead of each
+// paying for its own round trip.
+func (r *UffdHTTPReader) drainPendingFaults() []int64 {
+	var pages []int64
+	for {
+		pollfd := []unix.PollFd{{Fd: int32(r.Uffd.Fd()), Events: unix.POLLIN}}
+		n, err := unix.Poll(pollfd, 0)
+		if err != nil || n == 0 || pollfd[0].Revents&unix.POLLIN == 0 {
+			return pages
 		}
 
-		// Try to fill [0:toRead) in buf.
-		want := int(toRead)
-		var read int
-		for read < want {
-			n, err := r.File.ReadAt(buf[read:want], fileOffset+int64(read))
-			if err != nil {
-				if errors.Is(err, io.EOF) {
-					read += n
-					break
-				}
-				log.Fatalf("httpseek: HTTP ReadAt failed at offset %d: %v", fileOffset+int64(read), err)
-			}
-			read += n
-			if n == 0 {
-				break
-			}
+		msg, err := r.Uffd.ReadMsg()
+		if err != nil {
+			return pages
+		}
+		if msg.Event != uffd.UFFD_EVENT_PAGEFAULT {
+			log.Printf("httpseek: unexpected uffd event 0x%x", msg.Event)
+			continue
+		}
+		if idx, ok := r.faultPageIndex(uintptr(msg.GetPagefault().Address)); ok {
+			pages = append(pages, idx)
 		}
-		// Remaining bytes in buf stay zero.
 	}
+}
 
-	// Satisfy the fault using a full-page COPY to a page-aligned address.
+// installPage satisfies a single page's fault using a full-page COPY to a
+// page-aligned address. A failure here is recorded via setErr rather than
+// killing the process; the caller still marks the page done so no faulting
+// goroutine is left waiting forever.
+func (r *UffdHTTPReader) installPage(page int64, data []byte) {
+	pageAddr := r.base + uintptr(page)*uintptr(r.PageSize)
 	if _, err := r.Uffd.Copy(
 		pageAddr,
-		uintptr(unsafe.Pointer(&buf[0])),
-		pageSize,
+		uintptr(unsafe.Pointer(&data[0])),
+		r.PageSize,
 		0,
 	); err != nil {
-		log.Fatalf("httpseek: uffd.Copy failed at addr=0x%x: %v", pageAddr, err)
+		r.setErr(fmt.Errorf("httpseek: uffd.Copy failed at addr=0x%x: %w", pageAddr, err))
+	}
+}
+
+// installZero satisfies a fault for a page that lies entirely past
+// File.Size() using UFFDIO_ZEROPAGE, avoiding the allocation and copy of
+// an explicit zero buffer that a regular Copy would need. A failure here
+// is recorded via setErr rather than killing the process.
+func (r *UffdHTTPReader) installZero(page int64) {
+	pageAddr := r.base + uintptr(page)*uintptr(r.PageSize)
+	if _, err := r.Uffd.Zeropage(pageAddr, r.PageSize, 0); err != nil {
+		r.setErr(fmt.Errorf("httpseek: uffd.Zeropage failed at addr=0x%x: %w", pageAddr, err))
 	}
 }
 
@@ -198,6 +229,10 @@ func (r *UffdHTTPReader) Read(p []byte) (int, error) {
 	n := copy(p, r.data[r.pos:])
 	r.pos += int64(n)
 
+	if err := r.Err(); err != nil {
+		return n, err
+	}
+
 	if n < len(p) || r.pos >= int64(len(r.data)) {
 		return n, io.EOF
 	}
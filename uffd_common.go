@@ -0,0 +1,400 @@
+// SPDX-License-Identifier: BSD-2-Clause
+
+//go:build unix
+
+package httpseek
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync/atomic"
+)
+
+// Default values for UffdHTTPReader's page-fault batching knobs.
+// defaultMaxRangesPerRequest lives in blockconsts.go, shared with
+// blockcache.go.
+const (
+	defaultMaxCoalesceGap      = 4
+	defaultPrefetchWindow      = 4
+	defaultUffdPrefetchWorkers = 4
+)
+
+// Per-page prefetch state, tracked in UffdHTTPReader.pageState so a
+// background prefetch and a real fault for the same page don't race to
+// satisfy it twice.
+const (
+	pageUnfetched int32 = iota
+	pagePending
+	pageDone
+)
+
+// PrefetchStats reports how effective UffdHTTPReader's background
+// sequential-access prefetcher has been. Hits and Misses count every page
+// of a fault batch, so with ReadAheadPages set they include the batch's
+// read-ahead pages as well as the pages that actually faulted.
+type PrefetchStats struct {
+	Prefetched int64 // pages speculatively fetched ahead of a fault
+	Hits       int64 // batch pages already prefetched (or in flight)
+	Misses     int64 // batch pages that had to be fetched synchronously
+}
+
+// Ensure interface sanity
+var (
+	_ io.Closer = (*UffdHTTPReader)(nil)
+	_ io.Reader = (*UffdHTTPReader)(nil)
+)
+
+// roundUp rounds n up to a multiple of align (align must be a power of 2).
+func roundUp(n, align int) int {
+	return (n + align - 1) &^ (align - 1)
+}
+
+// setErr records err as the reader's terminal error if one isn't already
+// set, so the first failure wins and later faults don't clobber it.
+func (r *UffdHTTPReader) setErr(err error) {
+	r.errMu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.errMu.Unlock()
+}
+
+// Err returns the first fatal error encountered while servicing a page
+// fault, if any. Once set, Read returns it.
+func (r *UffdHTTPReader) Err() error {
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+	return r.err
+}
+
+// PrefetchStats returns a snapshot of background-prefetch effectiveness so
+// far: how many pages were prefetched, and how many real faults were
+// served by a prefetched (or in-flight prefetching) page versus had to
+// fall back to a synchronous fetch.
+func (r *UffdHTTPReader) PrefetchStats() PrefetchStats {
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	return r.stats
+}
+
+func (r *UffdHTTPReader) workerSem() chan struct{} {
+	r.prefetchSemOnce.Do(func() {
+		n := r.PrefetchWorkers
+		if n <= 0 {
+			n = defaultUffdPrefetchWorkers
+		}
+		r.prefetchSem = make(chan struct{}, n)
+	})
+	return r.prefetchSem
+}
+
+// handlePageFault batches the triggering fault together with any other
+// faults already queued (plus, if ReadAheadPages is set, a speculative run
+// of pages past the highest one faulted) into as few Range GETs as
+// possible, then satisfies every page in the batch via the backend's
+// install methods.
+func (r *UffdHTTPReader) handlePageFault(addr uintptr) {
+	first, ok := r.faultPageIndex(addr)
+	if !ok {
+		return
+	}
+	r.observeStride(first)
+
+	pages := make([]int64, 0, 1+r.ReadAheadPages)
+	pages = append(pages, first)
+	pages = append(pages, r.drainPendingFaults()...)
+	pages = r.addReadAhead(pages)
+	pages = dedupSortedPages(pages)
+
+	toFetch, hits := r.claimPages(pages)
+	r.statsMu.Lock()
+	r.stats.Hits += hits
+	r.stats.Misses += int64(len(toFetch))
+	r.statsMu.Unlock()
+
+	r.fillBatch(r.coalesceRuns(toFetch))
+}
+
+// claimPages atomically transitions each page from pageUnfetched to
+// pagePending, returning the pages this call won the race to fetch
+// itself. A page already pagePending or pageDone - whether because a
+// background prefetch claimed it first or already finished - counts as a
+// hit instead: fetching it again would either race with, or duplicate,
+// that prefetch.
+func (r *UffdHTTPReader) claimPages(pages []int64) (toFetch []int64, hits int64) {
+	for _, p := range pages {
+		if atomic.CompareAndSwapInt32(&r.pageState[p], pageUnfetched, pagePending) {
+			toFetch = append(toFetch, p)
+		} else {
+			hits++
+		}
+	}
+	return toFetch, hits
+}
+
+// observeStride records page in the fault history ring buffer and, once
+// the last PrefetchWindow faults form a monotonic run (each one page past
+// the last), kicks off a background prefetch of the pages that follow.
+func (r *UffdHTTPReader) observeStride(page int64) {
+	if r.PrefetchAhead <= 0 {
+		return
+	}
+	window := r.PrefetchWindow
+	if window <= 0 {
+		window = defaultPrefetchWindow
+	}
+
+	r.historyMu.Lock()
+	r.history = append(r.history, page)
+	if len(r.history) > window {
+		r.history = r.history[len(r.history)-window:]
+	}
+	history := append([]int64(nil), r.history...)
+	r.historyMu.Unlock()
+
+	if len(history) < window {
+		return
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i] != history[i-1]+1 {
+			return
+		}
+	}
+
+	r.triggerAsyncPrefetch(page + 1)
+}
+
+// triggerAsyncPrefetch speculatively fetches up to PrefetchAhead pages
+// starting at page start, each in its own bounded background worker, and
+// pre-satisfies them via the backend's install methods before the mutator
+// ever faults on them.
+func (r *UffdHTTPReader) triggerAsyncPrefetch(start int64) {
+	numPages := int64(len(r.pageState))
+	sem := r.workerSem()
+
+	for i := int64(0); i < int64(r.PrefetchAhead) && start+i < numPages; i++ {
+		page := start + i
+		if !atomic.CompareAndSwapInt32(&r.pageState[page], pageUnfetched, pagePending) {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			// Worker pool is saturated; leave this page pageUnfetched for
+			// a later fault (or prefetch pass) to pick up.
+			atomic.StoreInt32(&r.pageState[page], pageUnfetched)
+			continue
+		}
+
+		go func(page int64) {
+			defer func() { <-sem }()
+			r.prefetchPage(page)
+		}(page)
+	}
+}
+
+// prefetchPage fetches and satisfies a single page already claimed
+// (pagePending) by the caller, marking it pageDone when finished.
+func (r *UffdHTTPReader) prefetchPage(page int64) {
+	offset := page * int64(r.PageSize)
+	fileSize := r.File.Size()
+
+	if offset >= fileSize {
+		r.installZero(page)
+	} else {
+		end := offset + int64(r.PageSize)
+		if end > fileSize {
+			end = fileSize
+		}
+		buf := make([]byte, r.PageSize)
+		want := int(end - offset)
+		var read int
+		for read < want {
+			n, err := r.File.ReadAtContext(context.Background(), buf[read:want], offset+int64(read))
+			read += n
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				log.Printf("httpseek: background prefetch of page %d failed: %v", page, err)
+				atomic.StoreInt32(&r.pageState[page], pageUnfetched)
+				return
+			}
+			if n == 0 {
+				break
+			}
+		}
+		r.installPage(page, buf)
+	}
+
+	atomic.StoreInt32(&r.pageState[page], pageDone)
+	r.statsMu.Lock()
+	r.stats.Prefetched++
+	r.statsMu.Unlock()
+}
+
+// faultPageIndex converts a faulting address into a page index relative to
+// the start of the mapping, logging and reporting false if addr falls
+// outside the mapped region.
+func (r *UffdHTTPReader) faultPageIndex(addr uintptr) (int64, bool) {
+	pageSize := uintptr(r.PageSize)
+	pageAddr := addr &^ (pageSize - 1)
+
+	if pageAddr < r.base {
+		log.Printf("httpseek: page fault before base: addr=0x%x base=0x%x", addr, r.base)
+		return 0, false
+	}
+	pageIndex := int64((pageAddr - r.base) / pageSize)
+	fileOffset := pageIndex * int64(r.PageSize)
+	if fileOffset < 0 || fileOffset >= int64(r.mapLen) {
+		log.Printf("httpseek: page fault out of mapped range: addr=0x%x idx=%d off=%d", addr, pageIndex, fileOffset)
+		return 0, false
+	}
+	return pageIndex, true
+}
+
+// addReadAhead extends pages (already containing at least one entry) with
+// up to ReadAheadPages additional, not-yet-faulting pages past the highest
+// one present, bounded by the mapping's page count.
+func (r *UffdHTTPReader) addReadAhead(pages []int64) []int64 {
+	if r.ReadAheadPages <= 0 || len(pages) == 0 {
+		return pages
+	}
+	maxPage := pages[0]
+	for _, p := range pages[1:] {
+		if p > maxPage {
+			maxPage = p
+		}
+	}
+	numPages := int64(r.mapLen) / int64(r.PageSize)
+	for i := 1; i <= r.ReadAheadPages && maxPage+int64(i) < numPages; i++ {
+		pages = append(pages, maxPage+int64(i))
+	}
+	return pages
+}
+
+func dedupSortedPages(pages []int64) []int64 {
+	sort.Slice(pages, func(i, j int) bool { return pages[i] < pages[j] })
+	out := pages[:0]
+	for i, p := range pages {
+		if i == 0 || p != out[len(out)-1] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// pageRun is a contiguous run of page indices, [Start, Start+Count).
+type pageRun struct {
+	Start, Count int64
+}
+
+// coalesceRuns groups sorted, deduplicated page indices into contiguous
+// runs, pulling in not-yet-faulting pages that fall within MaxCoalesceGap
+// of the previous one rather than starting a new run (and a second round
+// trip) for them.
+func (r *UffdHTTPReader) coalesceRuns(pages []int64) []pageRun {
+	if len(pages) == 0 {
+		return nil
+	}
+	gap := r.MaxCoalesceGap
+	if gap <= 0 {
+		gap = defaultMaxCoalesceGap
+	}
+
+	var runs []pageRun
+	run := pageRun{Start: pages[0], Count: 1}
+	for _, p := range pages[1:] {
+		if p-(run.Start+run.Count-1) <= int64(gap) {
+			run.Count = p - run.Start + 1
+			continue
+		}
+		runs = append(runs, run)
+		run = pageRun{Start: p, Count: 1}
+	}
+	return append(runs, run)
+}
+
+// fillBatch satisfies every page across runs. The in-file portion of each
+// run is fetched in as few Range GETs as possible: runs are grouped into
+// requests of at most MaxRangesPerRequest ranges apiece and fetched with
+// ReadRangesContext, which issues one Range: bytes=a-b,c-d,... GET per
+// group and falls back to individual ranges itself if the server won't
+// honor multipart/byteranges. Any portion of a run beyond EOF, or that
+// couldn't be fetched, is left to the backend's installZero.
+func (r *UffdHTTPReader) fillBatch(runs []pageRun) {
+	fileSize := r.File.Size()
+	bufs := make([][]byte, len(runs))
+	for i, run := range runs {
+		bufs[i] = make([]byte, run.Count*int64(r.PageSize))
+	}
+
+	maxRanges := r.MaxRangesPerRequest
+	if maxRanges <= 0 {
+		maxRanges = defaultMaxRangesPerRequest
+	}
+
+	type pending struct {
+		runIdx     int
+		start, end int64 // file offsets
+	}
+	var todo []pending
+	for i, run := range runs {
+		start := run.Start * int64(r.PageSize)
+		end := start + run.Count*int64(r.PageSize)
+		if end > fileSize {
+			end = fileSize
+		}
+		if end > start {
+			todo = append(todo, pending{i, start, end})
+		}
+	}
+
+	for len(todo) > 0 {
+		n := len(todo)
+		if n > maxRanges {
+			n = maxRanges
+		}
+		batch := todo[:n]
+		todo = todo[n:]
+
+		ranges := make([]Range, len(batch))
+		for i, pd := range batch {
+			ranges[i] = Range{Start: pd.start, End: pd.end - 1}
+		}
+
+		data, err := r.File.ReadRangesContext(context.Background(), ranges)
+		if err != nil {
+			// Every faulted page still needs to be resolved or the faulting
+			// goroutine hangs forever, so we zero-fill the batch and
+			// surface the error via Err()/Read() instead of crashing.
+			r.setErr(fmt.Errorf("httpseek: batched range read failed: %w", err))
+			for _, pd := range batch {
+				bufs[pd.runIdx] = nil
+			}
+			continue
+		}
+		for i, pd := range batch {
+			copy(bufs[pd.runIdx], data[i])
+		}
+	}
+
+	for i, run := range runs {
+		for p := int64(0); p < run.Count; p++ {
+			pageIdx := run.Start + p
+			switch {
+			case pageIdx*int64(r.PageSize) >= fileSize:
+				r.installZero(pageIdx)
+			case bufs[i] == nil:
+				r.installZero(pageIdx)
+			default:
+				data := bufs[i][p*int64(r.PageSize) : (p+1)*int64(r.PageSize)]
+				r.installPage(pageIdx, data)
+			}
+			atomic.StoreInt32(&r.pageState[pageIdx], pageDone)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: BSD-2-Clause
+
+//go:build !linux && unix
+
+package httpseek
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// UffdHTTPReader maps a remote HTTP file into memory and fills pages on
+// demand, for platforms without Linux's userfaultfd (macOS, the BSDs).
+// See uffd_common.go for the platform-independent fault-batching and
+// prefetch logic shared with the Linux backend in uffd.go; this file
+// provides that shared logic's "install" backend: mprotect instead of
+// Uffd.Copy/Uffd.Zeropage.
+//
+// Without userfaultfd there's no kernel primitive to trap an arbitrary
+// goroutine's access to an unmapped page and transparently resume it, so
+// this backend can only resolve pages it knows are about to be touched.
+// Read resolves each page it's about to copy from before copying it,
+// which is correct and still demand-paged for any caller going through
+// Read. Bytes() returns the same mapping for callers that want direct
+// slice access, but - unlike the Linux backend - pages aren't faulted in
+// lazily on access: call Touch first for any range that Read hasn't
+// already covered.
+type UffdHTTPReader struct {
+	File     *HTTPFile
+	PageSize int
+
+	full []byte // full mmap'd region (page-aligned length), PROT_NONE until installed
+	data []byte // user-visible slice: len == file size
+
+	base   uintptr // start address of mapping
+	mapLen int     // page-aligned mapping length
+	pos    int64   // read offset for io.Reader
+
+	// MaxCoalesceGap is the largest gap, in pages, between two pages in a
+	// fault batch that still get folded into a single contiguous Range,
+	// pulling in the (not yet faulting) pages between them rather than
+	// paying for a second round trip later. Zero uses
+	// defaultMaxCoalesceGap.
+	MaxCoalesceGap int
+
+	// MaxRangesPerRequest caps how many byte ranges go into a single
+	// Range header; some servers refuse a multipart/byteranges response
+	// with too many parts. Zero uses defaultMaxRangesPerRequest.
+	MaxRangesPerRequest int
+
+	// ReadAheadPages extends a fault batch past its highest page by this
+	// many additional pages, speculatively filling them before they
+	// fault. Zero disables read-ahead.
+	ReadAheadPages int
+
+	// PrefetchWindow is how many recent fault page indices handlePageFault
+	// tracks to detect a monotonic access stride. Zero uses
+	// defaultPrefetchWindow.
+	PrefetchWindow int
+
+	// PrefetchAhead is how many pages past a detected monotonic stride get
+	// speculatively fetched and pre-satisfied in the background, before
+	// Read ever reaches them. Zero disables background prefetch.
+	PrefetchAhead int
+
+	// PrefetchWorkers bounds how many background prefetches can run
+	// concurrently, so a fast sequential scan doesn't flood the origin
+	// with unbounded parallel requests. Zero uses defaultUffdPrefetchWorkers.
+	PrefetchWorkers int
+
+	pageState []int32 // atomic per-page state: pageUnfetched/pagePending/pageDone
+
+	historyMu sync.Mutex
+	history   []int64 // ring buffer of recent fault page indices
+
+	prefetchSemOnce sync.Once
+	prefetchSem     chan struct{}
+
+	statsMu sync.Mutex
+	stats   PrefetchStats
+
+	errMu sync.Mutex
+	err   error // first fatal fetch error, surfaced by Read instead of crashing
+}
+
+// NewUffdHTTPReader maps a remote HTTP file, demand-paging it on Read via
+// mprotect instead of userfaultfd.
+func NewUffdHTTPReader(f *HTTPFile) (*UffdHTTPReader, error) {
+	pageSize := unix.Getpagesize()
+
+	n := int(f.Size())
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid size: %d", n)
+	}
+
+	mapLen := roundUp(n, pageSize)
+
+	// Anonymous mapping, initially inaccessible: every page starts
+	// unresolved and is mprotected to PROT_READ[|PROT_WRITE] by
+	// installPage/installZero once its content is known.
+	full, err := unix.Mmap(-1, 0, mapLen, unix.PROT_NONE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS)
+	if err != nil {
+		return nil, fmt.Errorf("mmap failed: %w", err)
+	}
+
+	r := &UffdHTTPReader{
+		File:      f,
+		full:      full,
+		data:      full[:n],
+		PageSize:  pageSize,
+		base:      uintptr(unsafe.Pointer(&full[0])),
+		mapLen:    mapLen,
+		pageState: make([]int32, mapLen/pageSize),
+	}
+	return r, nil
+}
+
+// drainPendingFaults has nothing to drain on this backend: faults are
+// resolved synchronously by ensurePage rather than queued by the kernel.
+func (r *UffdHTTPReader) drainPendingFaults() []int64 { return nil }
+
+// ensurePage blocks until page is resolved, triggering its fetch (batched
+// via the shared handlePageFault/fillBatch path) if no one has claimed it
+// yet, or waiting for a concurrent claim (a background prefetch, or
+// another Read) to finish if someone has.
+func (r *UffdHTTPReader) ensurePage(page int64) {
+	if atomic.LoadInt32(&r.pageState[page]) == pageDone {
+		return
+	}
+	r.handlePageFault(r.base + uintptr(page)*uintptr(r.PageSize))
+	for atomic.LoadInt32(&r.pageState[page]) != pageDone {
+		runtime.Gosched()
+	}
+}
+
+// installPage satisfies a page by mprotecting it read-write and copying
+// data in. A failure here is recorded via setErr rather than killing the
+// process; the caller still marks the page done so no faulting goroutine
+// is left waiting forever.
+func (r *UffdHTTPReader) installPage(page int64, data []byte) {
+	off := page * int64(r.PageSize)
+	if err := unix.Mprotect(r.full[off:off+int64(r.PageSize)], unix.PROT_READ|unix.PROT_WRITE); err != nil {
+		r.setErr(fmt.Errorf("httpseek: mprotect failed for page %d: %w", page, err))
+		return
+	}
+	copy(r.full[off:off+int64(len(data))], data)
+}
+
+// installZero satisfies a page that lies entirely past File.Size() by
+// mprotecting it readable. Anonymous private pages are already
+// kernel-zero-filled, so no explicit write is needed. A failure here is
+// recorded via setErr rather than killing the process.
+func (r *UffdHTTPReader) installZero(page int64) {
+	off := page * int64(r.PageSize)
+	if err := unix.Mprotect(r.full[off:off+int64(r.PageSize)], unix.PROT_READ); err != nil {
+		r.setErr(fmt.Errorf("httpseek: mprotect failed for zero page %d: %w", page, err))
+	}
+}
+
+// Read implements io.Reader on top of the mmap'd region, resolving each
+// page it's about to copy from before copying it.
+func (r *UffdHTTPReader) Read(p []byte) (int, error) {
+	if r.pos >= int64(len(r.data)) {
+		return 0, io.EOF
+	}
+
+	end := r.pos + int64(len(p))
+	if end > int64(len(r.data)) {
+		end = int64(len(r.data))
+	}
+
+	if err := r.Touch(r.pos, end-r.pos); err != nil {
+		return 0, err
+	}
+
+	n := copy(p, r.data[r.pos:end])
+	r.pos += int64(n)
+
+	if n < len(p) || r.pos >= int64(len(r.data)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Touch ensures every page in [off, off+length) is resolved, for callers
+// that access the slice returned by Bytes() directly instead of going
+// through Read. It's a no-op for any page already read or prefetched.
+func (r *UffdHTTPReader) Touch(off, length int64) error {
+	if length <= 0 {
+		return nil
+	}
+	end := off + length
+	if end > int64(len(r.data)) {
+		end = int64(len(r.data))
+	}
+	startPage := off / int64(r.PageSize)
+	endPage := (end - 1) / int64(r.PageSize)
+	for page := startPage; page <= endPage; page++ {
+		r.ensurePage(page)
+	}
+	return r.Err()
+}
+
+// Bytes returns the memory-mapped region representing the file contents.
+// Unlike the Linux backend, accessing it directly does not fault pages in
+// lazily; call Touch first for any range Read hasn't already covered.
+func (r *UffdHTTPReader) Bytes() []byte {
+	return r.data
+}
+
+// Close unmaps the backing memory.
+func (r *UffdHTTPReader) Close() error {
+	return unix.Munmap(r.full)
+}
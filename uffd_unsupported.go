@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: BSD-2-Clause
+
+//go:build !linux && !unix
+
+package httpseek
+
+import "fmt"
+
+// UffdHTTPReader is unavailable on this platform: demand-paged reads need
+// either Linux's userfaultfd (see uffd.go) or a POSIX mmap+mprotect
+// fallback (see uffd_fallback.go), and this target is neither. Rather
+// than fake a working API, NewUffdHTTPReader just reports that plainly;
+// callers needing a portable reader should use ReaderAtHTTP instead.
+type UffdHTTPReader struct{}
+
+// NewUffdHTTPReader always fails on this platform. See the UffdHTTPReader
+// doc comment.
+func NewUffdHTTPReader(f *HTTPFile) (*UffdHTTPReader, error) {
+	return nil, fmt.Errorf("httpseek: UffdHTTPReader is not supported on this platform")
+}